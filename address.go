@@ -0,0 +1,94 @@
+package fsm
+
+import (
+	"errors"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+// ErrNoTarget indicates what update has no chat or user
+// for addressing in storage. See Addressing.
+var ErrNoTarget = errors.New("fsm: update has no target chat or user")
+
+// Addressing describes how context resolves chat and user
+// of update for storage.
+//
+// Not every update has both of them. Channel posts have no
+// sender, inline queries, chosen inline results and poll
+// answers have no chat. Addressing fills them by fallbacks.
+// If chat or user still unknown context has no target:
+// Context.State returns DefaultState and changes of state
+// and data return ErrNoTarget.
+type Addressing struct {
+	// SenderChatAsUser uses sender chat as user for messages
+	// sent on behalf of chat: channel posts and messages
+	// of anonymous group admins. Callbacks are addressed
+	// by user who pressed button.
+	SenderChatAsUser bool
+
+	// UserAsChat uses user as chat for updates without chat.
+	// The key is the same as for private chat with user.
+	UserAsChat bool
+
+	// Topics uses message thread of forum topic in key.
+	// So every topic has independent state.
+	Topics bool
+
+	// Key changes resolved key by update, if it's set.
+	// It allows custom addressing what depends on update,
+	// for example see strategy.ByChatType.
+	Key func(c tele.Context, key StorageKey) StorageKey
+}
+
+// DefaultAddressing is addressing what uses NewFSMContext.
+var DefaultAddressing = Addressing{
+	SenderChatAsUser: true,
+	UserAsChat:       true,
+	Topics:           true,
+}
+
+// Resolve returns storage key for update.
+// If chat or user can't be resolved it returns false.
+// It never panics.
+func (a Addressing) Resolve(c tele.Context) (StorageKey, bool) {
+	var (
+		key              StorageKey
+		hasChat, hasUser bool
+	)
+
+	// message of callback is message of bot, not update itself
+	if msg := c.Message(); a.SenderChatAsUser && c.Callback() == nil && msg != nil && msg.SenderChat != nil {
+		key.UserID, hasUser = msg.SenderChat.ID, true
+	} else if sender := c.Sender(); sender != nil {
+		key.UserID, hasUser = sender.ID, true
+	}
+
+	if chat := c.Chat(); chat != nil {
+		key.ChatID, hasChat = chat.ID, true
+	} else if a.UserAsChat && hasUser {
+		key.ChatID, hasChat = key.UserID, true
+	}
+
+	if !hasChat || !hasUser {
+		return StorageKey{}, false
+	}
+
+	if msg := c.Message(); a.Topics && msg != nil && msg.TopicMessage {
+		key.ThreadID = msg.ThreadID
+	}
+	if a.Key != nil {
+		key = a.Key(c, key)
+	}
+	return key, true
+}
+
+// ContextMaker returns context maker what uses this addressing.
+//
+//	manager := fsm.NewManager(bot, nil, storage, fsm.Addressing{
+//		UserAsChat: true,
+//	}.ContextMaker())
+func (a Addressing) ContextMaker() ContextMakerFunc {
+	return func(c tele.Context, storage Storage) Context {
+		return newFSMContext(c, storage, a)
+	}
+}
@@ -0,0 +1,150 @@
+package fsm_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vitaliy-ukiru/fsm-telebot"
+	"github.com/vitaliy-ukiru/fsm-telebot/storages/memory"
+	tele "gopkg.in/telebot.v3"
+)
+
+func TestAddressing_Resolve(t *testing.T) {
+	var (
+		user    = &tele.User{ID: 20}
+		chat    = &tele.Chat{ID: 10}
+		channel = &tele.Chat{ID: -100, Type: tele.ChatChannel}
+		group   = &tele.Chat{ID: -200, Type: tele.ChatSuperGroup}
+		anonBot = &tele.User{ID: 1087968824}
+	)
+
+	tests := []struct {
+		name     string
+		update   tele.Update
+		fallback fsm.StorageKey // with DefaultAddressing
+		fallOk   bool
+		strict   fsm.StorageKey // with empty Addressing
+		strictOk bool
+	}{
+		{
+			name:     "message",
+			update:   tele.Update{Message: &tele.Message{Chat: chat, Sender: user}},
+			fallback: fsm.StorageKey{ChatID: 10, UserID: 20}, fallOk: true,
+			strict: fsm.StorageKey{ChatID: 10, UserID: 20}, strictOk: true,
+		},
+		{
+			name:     "topic message",
+			update:   tele.Update{Message: &tele.Message{Chat: group, Sender: user, ThreadID: 7, TopicMessage: true}},
+			fallback: fsm.StorageKey{ChatID: -200, UserID: 20, ThreadID: 7}, fallOk: true,
+			strict: fsm.StorageKey{ChatID: -200, UserID: 20}, strictOk: true,
+		},
+		{
+			name:     "reply in chat without topics",
+			update:   tele.Update{Message: &tele.Message{Chat: group, Sender: user, ThreadID: 3}},
+			fallback: fsm.StorageKey{ChatID: -200, UserID: 20}, fallOk: true,
+			strict: fsm.StorageKey{ChatID: -200, UserID: 20}, strictOk: true,
+		},
+		{
+			name:     "channel post",
+			update:   tele.Update{ChannelPost: &tele.Message{Chat: channel, SenderChat: channel}},
+			fallback: fsm.StorageKey{ChatID: -100, UserID: -100}, fallOk: true,
+		},
+		{
+			name:     "anonymous admin",
+			update:   tele.Update{Message: &tele.Message{Chat: group, Sender: anonBot, SenderChat: group}},
+			fallback: fsm.StorageKey{ChatID: -200, UserID: -200}, fallOk: true,
+			strict: fsm.StorageKey{ChatID: -200, UserID: anonBot.ID}, strictOk: true,
+		},
+		{
+			name:     "callback",
+			update:   tele.Update{Callback: &tele.Callback{Sender: user, Message: &tele.Message{Chat: chat}}},
+			fallback: fsm.StorageKey{ChatID: 10, UserID: 20}, fallOk: true,
+			strict: fsm.StorageKey{ChatID: 10, UserID: 20}, strictOk: true,
+		},
+		{
+			name:     "callback on channel post",
+			update:   tele.Update{Callback: &tele.Callback{Sender: user, Message: &tele.Message{Chat: channel, SenderChat: channel}}},
+			fallback: fsm.StorageKey{ChatID: -100, UserID: 20}, fallOk: true,
+			strict: fsm.StorageKey{ChatID: -100, UserID: 20}, strictOk: true,
+		},
+		{
+			name:     "inline callback",
+			update:   tele.Update{Callback: &tele.Callback{Sender: user, MessageID: "inline"}},
+			fallback: fsm.StorageKey{ChatID: 20, UserID: 20}, fallOk: true,
+		},
+		{
+			name:     "inline query",
+			update:   tele.Update{Query: &tele.Query{Sender: user}},
+			fallback: fsm.StorageKey{ChatID: 20, UserID: 20}, fallOk: true,
+		},
+		{
+			name:     "chosen inline result",
+			update:   tele.Update{InlineResult: &tele.InlineResult{Sender: user}},
+			fallback: fsm.StorageKey{ChatID: 20, UserID: 20}, fallOk: true,
+		},
+		{
+			name:     "poll answer",
+			update:   tele.Update{PollAnswer: &tele.PollAnswer{Sender: user}},
+			fallback: fsm.StorageKey{ChatID: 20, UserID: 20}, fallOk: true,
+		},
+		{
+			name:   "poll",
+			update: tele.Update{Poll: &tele.Poll{ID: "poll"}},
+		},
+	}
+
+	var b tele.Bot
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := b.NewContext(tt.update)
+
+			key, ok := fsm.DefaultAddressing.Resolve(c)
+			assert.Equalf(t, tt.fallOk, ok, "DefaultAddressing.Resolve ok")
+			assert.Equalf(t, tt.fallback, key, "DefaultAddressing.Resolve key")
+
+			key, ok = fsm.Addressing{}.Resolve(c)
+			assert.Equalf(t, tt.strictOk, ok, "Addressing{}.Resolve ok")
+			assert.Equalf(t, tt.strict, key, "Addressing{}.Resolve key")
+		})
+	}
+}
+
+func TestContext_NoTarget(t *testing.T) {
+	var b tele.Bot
+	c := b.NewContext(tele.Update{Query: &tele.Query{Sender: &tele.User{ID: 20}}})
+	state := fsm.Addressing{}.ContextMaker()(c, memory.NewStorage())
+
+	current, err := state.State()
+	require.NoError(t, err)
+	assert.Equal(t, fsm.DefaultState, current, "state without target")
+
+	var got string
+	assert.ErrorIs(t, state.Set("state"), fsm.ErrNoTarget, "Set")
+	assert.ErrorIs(t, state.Finish(true), fsm.ErrNoTarget, "Finish")
+	assert.ErrorIs(t, state.Update("key", "value"), fsm.ErrNoTarget, "Update")
+	assert.ErrorIs(t, state.Get("key", &got), fsm.ErrNoTarget, "Get")
+	assert.ErrorIs(t, state.ChatData().Update("key", "value"), fsm.ErrNoTarget, "ChatData")
+	assert.NoError(t, state.GlobalData().Update("key", "value"), "GlobalData")
+}
+
+func TestAddressing_KeyKeepsScopes(t *testing.T) {
+	base := memory.NewStorage()
+	maker := fsm.Addressing{Key: func(_ tele.Context, key fsm.StorageKey) fsm.StorageKey {
+		key.ChatID = 0
+		return key
+	}}.ContextMaker()
+	var b tele.Bot
+	state := maker(b.NewContext(textUpdate("")), base)
+
+	require.NoError(t, state.Set("state"))
+	require.NoError(t, state.ChatData().Update("lang", "chat"))
+
+	s, err := base.GetState(0, 20)
+	require.NoError(t, err)
+	assert.Equal(t, fsm.State("state"), s, "state by mapped key")
+
+	var got string
+	require.NoError(t, base.GetDataByKey(fsm.StorageKey{ChatID: 10, Machine: "__fsm_chat"}, "lang", &got))
+	assert.Equal(t, "chat", got, "chat data by origin key")
+}
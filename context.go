@@ -1,6 +1,8 @@
 package fsm
 
 import (
+	"errors"
+
 	tele "gopkg.in/telebot.v3"
 )
 
@@ -11,14 +13,42 @@ type Context interface {
 	Bot() *tele.Bot
 
 	// State returns current state for sender.
+	// For updates without target it returns DefaultState
+	// (see Addressing).
 	State() (State, error)
 
 	// Set state for sender.
+	//
+	// If sender leaves StateGroup data by associated
+	// keys of group will be deleted (see StateGroup.Key).
 	Set(state State) error
 
 	// Finish state for sender and deletes data if arg provided.
+	//
+	// Data by associated keys of current StateGroup
+	// deletes in any case (see StateGroup.Key).
 	Finish(deleteData bool) error
 
+	// NextIn sets next state of group relative to current state
+	// and returns it. If current state is last in group it sets
+	// DefaultState (see StateGroup.Next).
+	//
+	// Returns ErrStateNotInGroup if current state is not in group.
+	//
+	// If storage implements StateSwapper state is changed only
+	// if it wasn't changed after reading, otherwise it returns
+	// ErrStateChanged. So double tap doesn't move sender twice.
+	// Other storages need serialized updates (see ManagerOf.Serialize).
+	NextIn(group *StateGroup) (State, error)
+
+	// PrevIn sets previous state of group relative to current
+	// state and returns it. If current state is first in group
+	// it sets DefaultState (see StateGroup.Previous).
+	//
+	// Returns ErrStateNotInGroup if current state is not in group.
+	// It changes state like NextIn.
+	PrevIn(group *StateGroup) (State, error)
+
 	// Update data in storage. When data argument is nil it must
 	// delete this item.
 	Update(key string, data any) error
@@ -30,21 +60,69 @@ type Context interface {
 	// MustGet returns data from storage and save it into `to` ignoring errors.
 	// Destination argument must be a valid pointer.
 	MustGet(key string, to any)
+
+	// Machine returns context of named state machine for the
+	// same sender. Every machine has own state and data.
+	// Empty name is default machine.
+	//
+	// Storage must implement KeyStorage for work with
+	// non-default machines.
+	Machine(name string) Context
+
+	// ChatData returns data shared by all users of chat.
+	ChatData() Data
+
+	// UserData returns data of sender shared across all chats.
+	UserData() Data
+
+	// GlobalData returns data shared by the whole bot.
+	GlobalData() Data
+
+	// Session returns id of current session. Session starts when
+	// sender enters any state from DefaultState and ends when
+	// sender returns to it (including Finish). Outside of session
+	// it returns empty string.
+	//
+	// Use it for correlation of updates of one flow run
+	// in logs, analytics, etc.
+	Session() (string, error)
 }
 
 type fsmContext struct {
-	s          Storage
-	c          tele.Context
-	chat, user int64
+	s   KeyStorage
+	c   tele.Context
+	key StorageKey
+
+	// origin is key before Addressing.Key.
+	// Data scopes use it.
+	origin StorageKey
+
+	// noTarget is true if key isn't resolved.
+	noTarget bool
 }
 
 // NewFSMContext returns new builtin FSM Context.
+// It resolves chat and user by DefaultAddressing.
 func NewFSMContext(c tele.Context, storage Storage) Context {
+	return newFSMContext(c, storage, DefaultAddressing)
+}
+
+func newFSMContext(c tele.Context, storage Storage, a Addressing) *fsmContext {
+	mapKey := a.Key
+	a.Key = nil
+
+	origin, ok := a.Resolve(c)
+	key := origin
+	if ok && mapKey != nil {
+		key = mapKey(c, key)
+	}
+
 	return &fsmContext{
-		c:    c,
-		s:    storage,
-		chat: c.Chat().ID,
-		user: c.Sender().ID,
+		c:        c,
+		s:        AsKeyStorage(storage),
+		key:      key,
+		origin:   origin,
+		noTarget: !ok,
 	}
 }
 
@@ -53,25 +131,171 @@ func (f *fsmContext) Bot() *tele.Bot {
 }
 
 func (f *fsmContext) State() (State, error) {
-	return f.s.GetState(f.chat, f.user)
+	if f.noTarget {
+		return DefaultState, nil
+	}
+	return f.s.GetStateByKey(f.key)
 }
 
 func (f *fsmContext) Set(state State) error {
-	return f.s.SetState(f.chat, f.user, state)
+	if f.noTarget {
+		return ErrNoTarget
+	}
+	current, err := f.State()
+	if err != nil {
+		return err
+	}
+	if err := f.transit(current, state); err != nil {
+		return err
+	}
+	return f.s.SetStateByKey(f.key, state)
+}
+
+// transit applies effects of transition between states:
+// group data, session and jobs. State isn't changed.
+func (f *fsmContext) transit(current, next State) error {
+	if err := f.leaveGroup(current, next); err != nil {
+		return err
+	}
+	if err := f.updateSession(current, next); err != nil {
+		return err
+	}
+	if current != next {
+		return f.cancelTransitionJobs()
+	}
+	return nil
 }
 
 func (f *fsmContext) Finish(deleteData bool) error {
-	return f.s.ResetState(f.chat, f.user, deleteData)
+	if f.noTarget {
+		return ErrNoTarget
+	}
+	if !deleteData {
+		current, err := f.State()
+		if err != nil {
+			return err
+		}
+		if err := f.leaveGroup(current, DefaultState); err != nil {
+			return err
+		}
+		if err := f.updateSession(current, DefaultState); err != nil {
+			return err
+		}
+	}
+	if err := f.cancelTransitionJobs(); err != nil {
+		return err
+	}
+	return f.s.ResetStateByKey(f.key, deleteData)
+}
+
+// leaveGroup deletes data by associated keys of current
+// state group if next state is not in this group.
+func (f *fsmContext) leaveGroup(current, next State) error {
+	if !hasGroupKeys() {
+		return nil
+	}
+
+	prefix, keys := groupKeys(current)
+	if nextPrefix, _ := next.Parse(); nextPrefix == prefix {
+		return nil
+	}
+
+	for _, key := range keys {
+		if err := f.s.UpdateDataByKey(f.key, key, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *fsmContext) NextIn(group *StateGroup) (State, error) {
+	return f.moveIn(group, group.Next)
+}
+
+func (f *fsmContext) PrevIn(group *StateGroup) (State, error) {
+	return f.moveIn(group, group.Previous)
+}
+
+// moveIn sets state what returns move for current state.
+// Current state must be in group.
+func (f *fsmContext) moveIn(group *StateGroup, move func(State) State) (State, error) {
+	current, err := f.State()
+	if err != nil {
+		return DefaultState, err
+	}
+	if !group.Contains(current) {
+		return current, ErrStateNotInGroup
+	}
+
+	next := move(current)
+	if swapper, ok := f.s.(StateSwapper); ok {
+		swapped, err := swapper.SwapStateByKey(f.key, current, next)
+		if !errors.Is(err, ErrNotSupported) {
+			if err != nil {
+				return current, err
+			}
+			if !swapped {
+				return current, ErrStateChanged
+			}
+			return next, f.transit(current, next)
+		}
+	}
+
+	// state can be changed between calls
+	if err := f.Set(next); err != nil {
+		return current, err
+	}
+	return next, nil
 }
 
 func (f *fsmContext) Update(key string, data any) error {
-	return f.s.UpdateData(f.chat, f.user, key, data)
+	if f.noTarget {
+		return ErrNoTarget
+	}
+	return f.s.UpdateDataByKey(f.key, key, data)
 }
 
 func (f *fsmContext) Get(key string, to any) error {
-	return f.s.GetData(f.chat, f.user, key, to)
+	if f.noTarget {
+		return ErrNoTarget
+	}
+	return f.s.GetDataByKey(f.key, key, to)
 }
 
 func (f *fsmContext) MustGet(key string, to any) {
-	_ = f.s.GetData(f.chat, f.user, key, to)
+	_ = f.Get(key, to)
+}
+
+func (f *fsmContext) ChatData() Data {
+	if f.noTarget {
+		return noTargetData{}
+	}
+	return f.scope(chatScope)
+}
+
+func (f *fsmContext) UserData() Data {
+	if f.noTarget {
+		return noTargetData{}
+	}
+	return f.scope(userScope)
+}
+
+func (f *fsmContext) GlobalData() Data {
+	return f.scope(globalScope)
+}
+
+// scope returns data of scope in storage under wrappers.
+// Addressing of wrappers (like strategy.Storage) must not
+// mix scopes.
+func (f *fsmContext) scope(scope func(StorageKey) StorageKey) Data {
+	return scopedData{
+		s:   AsKeyStorage(UnwrapStorage(f.s)),
+		key: scope(f.origin),
+	}
+}
+
+func (f *fsmContext) Machine(name string) Context {
+	machine := *f
+	machine.key.Machine = name
+	return &machine
 }
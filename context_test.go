@@ -0,0 +1,283 @@
+package fsm_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vitaliy-ukiru/fsm-telebot"
+	"github.com/vitaliy-ukiru/fsm-telebot/storages/memory"
+	"github.com/vitaliy-ukiru/fsm-telebot/storages/strategy"
+	tele "gopkg.in/telebot.v3"
+)
+
+func newTestContext(t *testing.T, storage fsm.Storage) fsm.Context {
+	t.Helper()
+	var b tele.Bot
+	c := b.NewContext(tele.Update{
+		Message: &tele.Message{
+			Chat:   &tele.Chat{ID: 10},
+			Sender: &tele.User{ID: 20},
+		},
+	})
+	return fsm.NewFSMContext(c, storage)
+}
+
+func TestContext_NextIn(t *testing.T) {
+	sg := fsm.NewStateGroup("reg", "name", "age")
+	state := newTestContext(t, memory.NewStorage())
+
+	_, err := state.NextIn(sg)
+	assert.ErrorIs(t, err, fsm.ErrStateNotInGroup, "NextIn from default state")
+
+	require.NoError(t, state.Set(sg.First()))
+
+	next, err := state.NextIn(sg)
+	require.NoError(t, err)
+	assert.Equal(t, currentState(t, state), next, "stored state")
+	assert.Equal(t, fsm.State("reg@age"), next)
+
+	prev, err := state.PrevIn(sg)
+	require.NoError(t, err)
+	assert.Equal(t, fsm.State("reg@name"), prev)
+
+	require.NoError(t, state.Set(sg.Last()))
+	next, err = state.NextIn(sg)
+	require.NoError(t, err)
+	assert.Equal(t, fsm.DefaultState, next, "NextIn from last state")
+}
+
+// tapStorage moves state once after first read of state,
+// like other update what is handled at the same time.
+type tapStorage struct {
+	*memory.Storage
+	tapped bool
+}
+
+func (s *tapStorage) GetStateByKey(key fsm.StorageKey) (fsm.State, error) {
+	state, err := s.Storage.GetStateByKey(key)
+	if !s.tapped {
+		s.tapped = true
+		_ = s.Storage.SetStateByKey(key, "reg@age")
+	}
+	return state, err
+}
+
+func TestContext_NextInConcurrent(t *testing.T) {
+	sg := fsm.NewStateGroup("reg", "name", "age", "city")
+	storage := memory.NewStorage()
+	require.NoError(t, storage.SetState(10, 20, sg.First()))
+
+	state := newTestContext(t, &tapStorage{Storage: storage})
+	_, err := state.NextIn(sg)
+	assert.ErrorIs(t, err, fsm.ErrStateChanged, "double tap")
+
+	got, err := storage.GetState(10, 20)
+	require.NoError(t, err)
+	assert.Equal(t, fsm.State("reg@age"), got, "state is moved once")
+}
+
+// currentState returns current state or fails test.
+func currentState(t *testing.T, state fsm.Context) fsm.State {
+	t.Helper()
+	s, err := state.State()
+	require.NoError(t, err)
+	return s
+}
+
+func TestContext_Machine(t *testing.T) {
+	state := newTestContext(t, memory.NewStorage())
+	cart := state.Machine("cart")
+
+	require.NoError(t, state.Set("reg@name"))
+	require.NoError(t, state.Update("item", "form"))
+	require.NoError(t, cart.Set("cart@items"))
+	require.NoError(t, cart.Update("item", "apple"))
+
+	assert.Equal(t, fsm.State("reg@name"), currentState(t, state), "default machine state")
+	assert.Equal(t, fsm.State("cart@items"), currentState(t, cart), "cart machine state")
+
+	var item string
+	require.NoError(t, cart.Get("item", &item))
+	assert.Equal(t, "apple", item, "cart machine data")
+
+	require.NoError(t, cart.Finish(true))
+	assert.Equal(t, fsm.State("reg@name"), currentState(t, state), "default machine after finish cart")
+	assert.ErrorIs(t, cart.Get("item", &item), fsm.ErrNotFound, "cart data after finish")
+	require.NoError(t, state.Get("item", &item))
+	assert.Equal(t, "form", item, "default machine data after finish cart")
+}
+
+type baseStorage struct{ fsm.Storage }
+
+func TestContext_MachineNotSupported(t *testing.T) {
+	state := newTestContext(t, baseStorage{memory.NewStorage()})
+
+	assert.NoError(t, state.Set("reg@name"), "default machine")
+	assert.ErrorIs(t, state.Machine("cart").Set("cart@items"), fsm.ErrNotSupported, "named machine")
+}
+
+func TestContext_ScopedData(t *testing.T) {
+	base := memory.NewStorage()
+	state := newTestContext(t, strategy.NewStorage(base, strategy.User))
+
+	require.NoError(t, state.Update("lang", "user-chat"))
+	require.NoError(t, state.ChatData().Update("lang", "chat"))
+	require.NoError(t, state.UserData().Update("lang", "user"))
+	require.NoError(t, state.GlobalData().Update("lang", "global"))
+
+	tests := []struct {
+		name string
+		key  fsm.StorageKey
+		want string
+	}{
+		{name: "chat data", key: fsm.StorageKey{ChatID: 10, Machine: "__fsm_chat"}, want: "chat"},
+		{name: "user data", key: fsm.StorageKey{UserID: 20, Machine: "__fsm_user"}, want: "user"},
+		{name: "global data", key: fsm.StorageKey{Machine: "__fsm_global"}, want: "global"},
+		{name: "sender data", key: fsm.StorageKey{UserID: 20}, want: "user-chat"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			require.NoError(t, base.GetDataByKey(tt.key, "lang", &got))
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	// strategy.User addresses sender by user, but scopes are separated
+	require.NoError(t, state.Finish(true))
+	var got string
+	require.NoError(t, state.UserData().Get("lang", &got))
+	assert.Equal(t, "user", got, "user data after Finish")
+}
+
+func TestContext_LeaveGroup(t *testing.T) {
+	sg := fsm.NewStateGroup("leave", "first", "second")
+	nameKey := sg.Key("name")
+	assert.Equal(t, "leave@name", nameKey, "group key")
+
+	state := newTestContext(t, memory.NewStorage())
+	require.NoError(t, state.Set(sg.First()))
+	require.NoError(t, state.Update(nameKey, "foo"))
+	require.NoError(t, state.Update("other", "bar"))
+
+	var got string
+	require.NoError(t, state.Set(sg.Last()))
+	require.NoError(t, state.Get(nameKey, &got), "data after move inside group")
+
+	require.NoError(t, state.Set("other_state"))
+	assert.ErrorIs(t, state.Get(nameKey, &got), fsm.ErrNotFound, "group data after leave group")
+	require.NoError(t, state.Get("other", &got), "other data after leave group")
+
+	require.NoError(t, state.Set(sg.First()))
+	require.NoError(t, state.Update(nameKey, "foo"))
+	require.NoError(t, state.Finish(false))
+	assert.ErrorIs(t, state.Get(nameKey, &got), fsm.ErrNotFound, "group data after finish")
+	require.NoError(t, state.Get("other", &got), "other data after finish")
+}
+
+func TestManager_ContextFor(t *testing.T) {
+	base := memory.NewStorage()
+	storage := strategy.NewStorage(base, strategy.User)
+	m := fsm.NewManager(newTestBot(t), nil, storage, nil)
+
+	state := m.ContextFor(10, 20)
+	require.NoError(t, state.Set("request@approved"))
+	require.NoError(t, state.Update("comment", "ok"))
+
+	// strategy of manager storage is applied
+	got, err := storage.GetState(30, 20)
+	require.NoError(t, err)
+	assert.Equal(t, fsm.State("request@approved"), got)
+
+	got, err = base.GetState(0, 20)
+	require.NoError(t, err)
+	assert.Equal(t, fsm.State("request@approved"), got)
+
+	var comment string
+	require.NoError(t, m.ContextFor(30, 20).Get("comment", &comment))
+	assert.Equal(t, "ok", comment)
+}
+
+func TestManager_ContextForChatType(t *testing.T) {
+	storage := memory.NewStorage()
+	m := fsm.NewManager(newTestBot(t), nil, storage,
+		strategy.ByChatType(strategy.User, strategy.Chat).ContextMaker(),
+	)
+
+	require.NoError(t, m.ContextFor(5, 5).Set("private"))
+	require.NoError(t, m.ContextFor(-10, 5).Set("group"))
+
+	got, err := storage.GetState(0, 5)
+	require.NoError(t, err)
+	assert.Equal(t, fsm.State("private"), got, "private chat is addressed by user")
+
+	got, err = storage.GetState(-10, 0)
+	require.NoError(t, err)
+	assert.Equal(t, fsm.State("group"), got, "group is addressed by chat")
+}
+
+func TestContext_Session(t *testing.T) {
+	state := newTestContext(t, memory.NewStorage())
+
+	session := func() string {
+		t.Helper()
+		id, err := state.Session()
+		require.NoError(t, err)
+		return id
+	}
+
+	assert.Empty(t, session(), "session in default state")
+
+	require.NoError(t, state.Set("reg@name"))
+	first := session()
+	assert.NotEmpty(t, first, "session started")
+
+	require.NoError(t, state.Set("reg@age"))
+	assert.Equal(t, first, session(), "session kept inside flow")
+
+	require.NoError(t, state.Finish(false))
+	assert.Empty(t, session(), "session ended by Finish")
+
+	require.NoError(t, state.Set("reg@name"))
+	second := session()
+	assert.NotEmpty(t, second)
+	assert.NotEqual(t, first, second, "new session for new flow run")
+
+	require.NoError(t, state.Set(fsm.DefaultState))
+	assert.Empty(t, session(), "session ended by DefaultState")
+}
+
+func TestContext_SessionReset(t *testing.T) {
+	storage := memory.NewStorage()
+	state := newTestContext(t, storage)
+	require.NoError(t, state.Set("reg@name"))
+
+	// reset by storage doesn't end session by context
+	require.NoError(t, storage.ResetState(10, 20, false))
+	id, err := state.Session()
+	require.NoError(t, err)
+	assert.Empty(t, id, "session after reset")
+
+	require.NoError(t, state.Set("reg@name"))
+	id, err = state.Session()
+	require.NoError(t, err)
+	assert.NotEmpty(t, id, "new session")
+}
+
+func TestContext_LeaveGroupSharedPrefix(t *testing.T) {
+	first := fsm.NewStateGroup("shared", "a").WithKeys("k1")
+	second := fsm.NewStateGroup("shared", "b").WithKeys("k2")
+	assert.Equal(t, []string{"shared@k1"}, first.Keys(), "keys of group")
+
+	state := newTestContext(t, memory.NewStorage())
+	require.NoError(t, state.Set(first.First()))
+	require.NoError(t, state.Update("shared@k1", "v1"))
+	require.NoError(t, state.Update("shared@k2", "v2"))
+	require.NoError(t, state.Set(second.First()))
+	require.NoError(t, state.Finish(false))
+
+	var got string
+	assert.ErrorIs(t, state.Get("shared@k1", &got), fsm.ErrNotFound, "key of first group")
+	assert.ErrorIs(t, state.Get("shared@k2", &got), fsm.ErrNotFound, "key of second group")
+}
@@ -0,0 +1,105 @@
+package fsm
+
+// Data is access to data in some scope of storage.
+// Context itself works with data of sender.
+//
+// See Context.ChatData, Context.UserData and Context.GlobalData.
+type Data interface {
+	// Update data in storage. When data argument is nil it must
+	// delete this item.
+	Update(key string, data any) error
+
+	// Get data from storage and save it into `to` argument.
+	// Destination argument must be a valid pointer.
+	Get(key string, to any) error
+
+	// MustGet returns data from storage and save it into `to` ignoring errors.
+	// Destination argument must be a valid pointer.
+	MustGet(key string, to any)
+}
+
+// Reserved machines of data scopes. They separate records of
+// scopes from records of senders, so addressing of storage
+// (like strategy.User) can't mix them. Names of machines
+// with prefix "__fsm" are reserved.
+const (
+	chatScopeMachine   = "__fsm_chat"
+	userScopeMachine   = "__fsm_user"
+	globalScopeMachine = "__fsm_global"
+)
+
+// Scopes of data map to keys in underlying storage:
+//
+//	chat:   StorageKey{ChatID: chat, Machine: "__fsm_chat"}
+//	user:   StorageKey{UserID: user, Machine: "__fsm_user"}
+//	global: StorageKey{Machine: "__fsm_global"}
+//
+// Storages without KeyStorage ignore machine of scopes (see AsKeyStorage).
+func chatScope(key StorageKey) StorageKey {
+	return StorageKey{ChatID: key.ChatID, Machine: chatScopeMachine}
+}
+
+func userScope(key StorageKey) StorageKey {
+	return StorageKey{UserID: key.UserID, Machine: userScopeMachine}
+}
+
+func globalScope(StorageKey) StorageKey {
+	return StorageKey{Machine: globalScopeMachine}
+}
+
+// isReservedMachine reports whether machine is reserved
+// by data scope or manager.
+func isReservedMachine(machine string) bool {
+	switch machine {
+	case chatScopeMachine, userScopeMachine, globalScopeMachine, updatesMachine, scheduleMachine:
+		return true
+	}
+	return false
+}
+
+// scopedData is Data for one key of storage.
+type scopedData struct {
+	s   KeyStorage
+	key StorageKey
+}
+
+func (d scopedData) Update(key string, data any) error {
+	return d.s.UpdateDataByKey(d.key, key, data)
+}
+
+func (d scopedData) Get(key string, to any) error {
+	return d.s.GetDataByKey(d.key, key, to)
+}
+
+func (d scopedData) MustGet(key string, to any) {
+	_ = d.s.GetDataByKey(d.key, key, to)
+}
+
+// noTargetData is Data of context without target.
+type noTargetData struct{}
+
+func (noTargetData) Update(string, any) error { return ErrNoTarget }
+func (noTargetData) Get(string, any) error    { return ErrNoTarget }
+func (noTargetData) MustGet(string, any)      {}
+
+// storageWrapper is storage what works over other storage.
+// For example, strategy.Storage.
+type storageWrapper interface {
+	Unwrap() Storage
+}
+
+// UnwrapStorage returns underlying storage of wrappers chain.
+// Wrappers implement method:
+//
+//	Unwrap() fsm.Storage
+//
+// If storage isn't wrapper returns it.
+func UnwrapStorage(storage Storage) Storage {
+	for {
+		w, ok := storage.(storageWrapper)
+		if !ok {
+			return storage
+		}
+		storage = w.Unwrap()
+	}
+}
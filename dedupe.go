@@ -0,0 +1,112 @@
+package fsm
+
+import (
+	"errors"
+	"strings"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+// updatesKey is data key of processed update IDs.
+const updatesKey = "__fsm_updates"
+
+// updatesMachine is reserved machine for processed update IDs
+// of user. It isn't data of user, see IsInternalMachine.
+const updatesMachine = "__fsm_updates"
+
+// Deduplicate enables skipping of updates what already were
+// processed. Manager records last `window` update IDs of every
+// user in storage and skips update before dispatch if its ID
+// was recorded.
+//
+// Update ID is recorded only after handler succeeded, so failed
+// update will be processed on redelivery. Zero or negative window
+// disables deduplication.
+//
+// Concurrent processing of duplicates is prevented only
+// with serialization (see Serialize).
+func (m *ManagerOf[C]) Deduplicate(window int) {
+	m.dedupWindow = window
+}
+
+// IsInternalMachine reports whether machine is used by manager
+// for internal records (like IDs of processed updates
+// and scheduled timeouts and jobs).
+// They aren't data of user, so storages skip them
+// in export (see UserRecordsStorage).
+func IsInternalMachine(machine string) bool {
+	return machine == updatesMachine || machine == scheduleMachine
+}
+
+// IsInternalDataKey reports whether data key is used by manager
+// for internal values of record (like session id, see Context.Session).
+// Prefix "__fsm_" of data keys is reserved. Storages skip them in
+// export like internal machines.
+func IsInternalDataKey(key string) bool {
+	return strings.HasPrefix(key, "__fsm_")
+}
+
+// processedUpdates returns data of processed updates and ID
+// of update. If deduplication isn't applicable returns false.
+func (m *ManagerOf[C]) processedUpdates(c tele.Context) (Data, int, bool) {
+	if m.dedupWindow <= 0 {
+		return nil, 0, false
+	}
+
+	id := c.Update().ID
+	if id == 0 { // update isn't from telegram
+		return nil, 0, false
+	}
+
+	key, ok := DefaultAddressing.Resolve(c)
+	if !ok {
+		return nil, 0, false
+	}
+	data := scopedData{
+		s:   AsKeyStorage(UnwrapStorage(m.store)),
+		key: StorageKey{UserID: key.UserID, Machine: updatesMachine},
+	}
+	return data, id, true
+}
+
+// isDuplicate reports whether update was processed.
+func (m *ManagerOf[C]) isDuplicate(c tele.Context) (bool, error) {
+	data, id, ok := m.processedUpdates(c)
+	if !ok {
+		return false, nil
+	}
+
+	var ids []int
+	if err := data.Get(updatesKey, &ids); err != nil && !errors.Is(err, ErrNotFound) {
+		return false, err
+	}
+	for _, processed := range ids {
+		if processed == id {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// markProcessed records update as processed.
+func (m *ManagerOf[C]) markProcessed(c tele.Context) error {
+	data, id, ok := m.processedUpdates(c)
+	if !ok {
+		return nil
+	}
+
+	var ids []int
+	if err := data.Get(updatesKey, &ids); err != nil && !errors.Is(err, ErrNotFound) {
+		return err
+	}
+
+	// new slice, because storage can keep old slice
+	if len(ids) >= m.dedupWindow {
+		ids = ids[len(ids)-m.dedupWindow+1:]
+	}
+	next := make([]int, 0, len(ids)+1)
+	next = append(next, ids...)
+	next = append(next, id)
+
+	return data.Update(updatesKey, next)
+}
@@ -0,0 +1,66 @@
+package fsm_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vitaliy-ukiru/fsm-telebot"
+	"github.com/vitaliy-ukiru/fsm-telebot/storages/memory"
+	tele "gopkg.in/telebot.v3"
+)
+
+func TestManager_Deduplicate(t *testing.T) {
+	bot := newTestBot(t)
+	m := fsm.NewManager(bot, nil, memory.NewStorage(), nil)
+	m.Deduplicate(2)
+
+	var handled []int
+	m.Bind(tele.OnText, fsm.AnyState, func(c tele.Context, _ fsm.Context) error {
+		handled = append(handled, c.Update().ID)
+		return nil
+	})
+
+	for _, id := range []int{1, 2, 1, 3, 1, 3} {
+		u := textUpdate("hello")
+		u.ID = id
+		bot.ProcessUpdate(u)
+	}
+
+	// window contains 2 last IDs, so 1 is forgotten after 3
+	assert.Equal(t, []int{1, 2, 3, 1}, handled)
+}
+
+func TestManager_DeduplicateFailed(t *testing.T) {
+	bot, err := tele.NewBot(tele.Settings{
+		Synchronous: true,
+		Offline:     true,
+		OnError:     func(error, tele.Context) {},
+	})
+	require.NoError(t, err)
+	storage := memory.NewStorage()
+	m := fsm.NewManager(bot, nil, storage, nil)
+	m.Deduplicate(2)
+
+	var calls int
+	m.Bind(tele.OnText, fsm.AnyState, func(c tele.Context, state fsm.Context) error {
+		calls++
+		if calls == 1 {
+			return errors.New("retry later")
+		}
+		return state.Finish(true)
+	})
+
+	u := textUpdate("hello")
+	u.ID = 1
+	bot.ProcessUpdate(u) // failed
+	bot.ProcessUpdate(u) // retry
+	bot.ProcessUpdate(u) // duplicate
+	assert.Equal(t, 2, calls, "failed update is processed again")
+
+	var buf bytes.Buffer
+	require.NoError(t, storage.ExportUser(20, &buf))
+	assert.NotContains(t, buf.String(), "__fsm", "internal records in export")
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"flag"
 	"fmt"
@@ -79,14 +80,6 @@ func main() {
 		}
 	}
 
-	defer func(fsmStorage *file.Storage) {
-		log.Println("saving storage state in Close")
-		err = fsmStorage.Close()
-		if err != nil {
-			log.Print("close storage error: ", err)
-		}
-	}(fsmStorage)
-
 	m := fsm.NewManager(bot, nil, fsmStorage, nil)
 	m.Group().Handle("/help", func(c tele.Context) error {
 		return c.Send(helpText)
@@ -131,12 +124,19 @@ func main() {
 		<-stopChan
 	}
 
-	// This operation too longs (~4 sec.)
-	// Don't wait in example.
-	// But in production code I think better don't use goroutine
+	// This operation too longs (~4 sec.), so don't wait it.
+	// Updates received while bot stops are dropped by manager.
 	go bot.Stop()
-	log.Println("bot stopped")
 
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Shutdown waits for running handlers and saves storage state.
+	log.Println("saving storage state in Shutdown")
+	if err := m.Shutdown(ctx); err != nil {
+		log.Print("shutdown error: ", err)
+	}
+	log.Println("bot stopped")
 }
 
 func GetDataHandler(c tele.Context, state fsm.Context) error {
@@ -25,6 +25,11 @@ var (
 	InputAgeState     = InputSG.New("age")
 	InputHobbyState   = InputSG.New("hobby")
 	InputConfirmState = InputSG.New("confirm")
+
+	// Data by this keys will be deleted after leaving form.
+	NameKey  = InputSG.Key("name")
+	AgeKey   = InputSG.Key("age")
+	HobbyKey = InputSG.Key("hobby")
 )
 
 var debug = flag.Bool("debug", false, "log debug info")
@@ -78,12 +83,13 @@ func main() {
 	manager.Bind(&cancelBtn, fsm.AnyState, OnCancelForm)
 
 	// form
-	manager.Bind(tele.OnText, InputNameState, OnInputName)
-	manager.Bind(tele.OnText, InputAgeState, OnInputAge)
-	manager.Bind(tele.OnText, InputHobbyState, OnInputHobby)
-	manager.Bind(&confirmBtn, InputConfirmState, OnInputConfirm, EditFormMessage("Now check y", "Y"))
-	manager.Bind(&resetFormBtn, InputConfirmState, OnInputResetForm, EditFormMessage("Now check your", "Your old"))
-	manager.Bind(&cancelInlineBtn, InputConfirmState, OnCancelForm, DeleteAfterHandler)
+	form := manager.ForGroup(InputSG)
+	form.Bind(tele.OnText, "name", OnInputName)
+	form.Bind(tele.OnText, "age", OnInputAge)
+	form.Bind(tele.OnText, "hobby", OnInputHobby)
+	form.Bind(&confirmBtn, "confirm", OnInputConfirm, EditFormMessage("Now check y", "Y"))
+	form.Bind(&resetFormBtn, "confirm", OnInputResetForm, EditFormMessage("Now check your", "Your old"))
+	form.Bind(&cancelInlineBtn, "confirm", OnCancelForm, DeleteAfterHandler)
 
 	log.Println("Handlers configured")
 	bot.Start()
@@ -108,14 +114,16 @@ func OnStartRegister(c tele.Context, state fsm.Context) error {
 	menu.Reply(menu.Row(cancelBtn))
 	menu.ResizeKeyboard = true
 
-	state.Set(InputNameState)
+	state.Set(InputSG.First())
 	return c.Send("Great! How your name?", menu)
 }
 
 func OnInputName(c tele.Context, state fsm.Context) error {
 	name := c.Message().Text
-	go state.Update("name", name)
-	go state.Set(InputAgeState)
+	go state.Update(NameKey, name)
+	if _, err := state.NextIn(InputSG); err != nil {
+		return err
+	}
 	return c.Send(fmt.Sprintf("Okay, %s. How old are you?", name))
 }
 
@@ -124,8 +132,10 @@ func OnInputAge(c tele.Context, state fsm.Context) error {
 	if err != nil || age <= 0 || age > 200 {
 		return c.Send("Incorrect age. Retry again.")
 	}
-	go state.Update("age", age)
-	go state.Set(InputHobbyState)
+	go state.Update(AgeKey, age)
+	if _, err := state.NextIn(InputSG); err != nil {
+		return err
+	}
 
 	return c.Send("Great! What is your hobby?")
 }
@@ -137,15 +147,17 @@ func OnInputHobby(c tele.Context, state fsm.Context) error {
 		m.Row(resetFormBtn, cancelInlineBtn),
 	)
 
-	go state.Update("hobby", c.Message().Text)
-	go state.Set(InputConfirmState)
+	go state.Update(HobbyKey, c.Message().Text)
+	if _, err := state.NextIn(InputSG); err != nil {
+		return err
+	}
 
 	var (
 		senderName string
 		senderAge  int
 	)
-	state.MustGet("name", &senderName)
-	state.MustGet("age", &senderAge)
+	state.MustGet(NameKey, &senderName)
+	state.MustGet(AgeKey, &senderAge)
 
 	c.Send("Wow, interesting!")
 	return c.Send(fmt.Sprintf(
@@ -160,15 +172,15 @@ func OnInputHobby(c tele.Context, state fsm.Context) error {
 }
 
 func OnInputConfirm(c tele.Context, state fsm.Context) error {
-	defer state.Finish(true)
+	defer state.Finish(false)
 	var (
 		senderName  string
 		senderAge   int
 		senderHobby string
 	)
-	state.MustGet("name", &senderName)
-	state.MustGet("age", &senderAge)
-	state.MustGet("hobby", &senderHobby)
+	state.MustGet(NameKey, &senderName)
+	state.MustGet(AgeKey, &senderAge)
+	state.MustGet(HobbyKey, &senderHobby)
 
 	data, _ := json.Marshal(tele.M{
 		"name":  senderName,
@@ -207,12 +219,12 @@ func OnCancelForm(c tele.Context, state fsm.Context) error {
 	menu.Reply(menu.Row(regBtn))
 	menu.ResizeKeyboard = true
 
-	go state.Finish(true)
+	go state.Finish(false)
 	return c.Send("Form entry canceled. Your input data has been deleted.", menu)
 }
 
 func OnInputResetForm(c tele.Context, state fsm.Context) error {
-	go state.Set(InputNameState)
+	go state.Set(InputSG.First())
 	c.Send("Okay! Start again.")
 	return c.Send("How your name?")
 }
@@ -8,6 +8,22 @@ import (
 type Filter struct {
 	Endpoint any
 	States   []State
+
+	// Machine is name of state machine what states checks.
+	// Empty value is default machine. See Context.Machine.
+	Machine string
+
+	// Storage of handler. Nil value is storage of manager
+	// or router (see RouterOf.SetStorage).
+	//
+	// It allows to use other addressing for handler:
+	//
+	//	manager.Handle(fsm.Filter{
+	//		Endpoint: "/poll",
+	//		States:   []fsm.State{fsm.DefaultState},
+	//		Storage:  strategy.NewStorage(storage, strategy.Chat),
+	//	}, OnPollSetup)
+	Storage Storage
 }
 
 // F returns new Filter object.
@@ -19,8 +35,8 @@ func F(endpoint any, states ...State) Filter {
 }
 
 // TelebotHandlerForState creates tele.Handler with local filter for given state.
-func (m *Manager) TelebotHandlerForState(want State, handler Handler) tele.HandlerFunc {
-	return m.HandlerAdapter(func(c tele.Context, state Context) error {
+func (m *ManagerOf[C]) TelebotHandlerForState(want State, handler HandlerOf[C]) tele.HandlerFunc {
+	return m.HandlerAdapter(func(c tele.Context, state C) error {
 		s, err := state.State()
 		if err != nil {
 			return &ErrHandlerState{Handler: "Manager.ForState", Err: err}
@@ -34,8 +50,8 @@ func (m *Manager) TelebotHandlerForState(want State, handler Handler) tele.Handl
 
 // TelebotHandlerForStates creates a handler with local filter
 // for current state to check for presence in given states.
-func (m *Manager) TelebotHandlerForStates(h Handler, states ...State) tele.HandlerFunc {
-	return m.HandlerAdapter(func(c tele.Context, state Context) error {
+func (m *ManagerOf[C]) TelebotHandlerForStates(h HandlerOf[C], states ...State) tele.HandlerFunc {
+	return m.HandlerAdapter(func(c tele.Context, state C) error {
 		s, err := state.State()
 		if err != nil {
 			return &ErrHandlerState{Handler: "Manager.ForStates", Err: err}
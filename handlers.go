@@ -2,6 +2,7 @@ package fsm
 
 import (
 	"fmt"
+	"reflect"
 
 	"github.com/vitaliy-ukiru/fsm-telebot/internal"
 	"github.com/vitaliy-ukiru/fsm-telebot/internal/container"
@@ -20,13 +21,20 @@ type handlerList = container.List[handlerEntry]
 // We can use switch-case in handler for check states, but I think not best practice.
 type handlerEntry struct {
 	states  container.Set[State]
+	machine string
+	storage Storage // nil is storage of manager
 	handler tele.HandlerFunc
 }
 
 // add handler to storage, just shortcut.
-func (hm handlerMapping) add(endpoint string, h tele.HandlerFunc, states []State) {
-	statesSet := container.HashSetFromSlice(states)
-	hm.insert(endpoint, handlerEntry{states: statesSet, handler: h})
+func (hm handlerMapping) add(endpoint string, h tele.HandlerFunc, f Filter) {
+	statesSet := container.HashSetFromSlice(f.States)
+	hm.insert(endpoint, handlerEntry{
+		states:  statesSet,
+		machine: f.Machine,
+		storage: f.Storage,
+		handler: h,
+	})
 }
 
 func (hm handlerMapping) insert(endpoint string, entry handlerEntry) {
@@ -38,38 +46,159 @@ func (hm handlerMapping) insert(endpoint string, entry handlerEntry) {
 }
 
 // forEndpoint returns handler what filters queries and execute correct handler.
-func (m *Manager) forEndpoint(endpoint string) tele.HandlerFunc {
+func (m *ManagerOf[C]) forEndpoint(endpoint string) tele.HandlerFunc {
 	return func(teleCtx tele.Context) error {
-		fsmCtx := m.contextMaker(teleCtx, m.store)
+		if !m.shutdown.enter() {
+			return nil
+		}
+		defer m.shutdown.leave()
 
-		state, err := fsmCtx.State()
+		unlock, ok, err := m.lockUpdate(teleCtx)
 		if err != nil {
-			return &ErrHandlerState{Handler: endpoint, Err: err}
+			return err
+		}
+		if !ok {
+			return nil
+		}
+		defer unlock()
+
+		if dup, err := m.isDuplicate(teleCtx); err != nil || dup {
+			return err
+		}
+
+		// contexts of storages, usually only one.
+		var contexts []*dispatchContext
+		contextOf := func(storage Storage) *dispatchContext {
+			for _, dc := range contexts {
+				if sameStorage(dc.storage, storage) {
+					return dc
+				}
+			}
+			dc := &dispatchContext{
+				storage: storage,
+				ctx:     m.contextMaker(teleCtx, m.storageOf(storage)),
+				states:  make(map[string]State),
+			}
+			contexts = append(contexts, dc)
+			return dc
 		}
 
-		h, ok := m.handlers.find(endpoint, state)
+		// states of machines, every state requests once.
+		stateOf := func(h handlerEntry) (State, error) {
+			dc := contextOf(h.storage)
+			if state, ok := dc.states[h.machine]; ok {
+				return state, nil
+			}
+
+			state, err := machineContext(dc.ctx, h.machine).State()
+			if err != nil {
+				return DefaultState, err
+			}
+			dc.states[h.machine] = state
+			return state, nil
+		}
+
+		h, ok, err := m.handlers.findFunc(endpoint, stateOf)
+		if err != nil {
+			return &ErrHandlerState{Handler: endpoint, Err: err}
+		}
 		if !ok {
 			return nil
 		}
 
 		// middlewares must be executed inside
 		// this handler for right work.
-		return h.handler(&wrapperContext{teleCtx, fsmCtx})
+		fsmCtx := contextOf(h.storage).ctx
+		if err := h.handler(&wrapperContext{
+			Context:    teleCtx,
+			fsmCtx:     machineContext(fsmCtx, h.machine),
+			dispatched: true,
+		}); err != nil {
+			return err
+		}
+		if err := m.markProcessed(teleCtx); err != nil {
+			return err
+		}
+
+		// timeouts work with states of manager storage only
+		if h.storage != nil {
+			return nil
+		}
+		return m.touchTimeout(teleCtx, fsmCtx)
 	}
 }
 
+// storageOf returns storage of handler. Nil is storage of manager.
+func (m *ManagerOf[C]) storageOf(storage Storage) Storage {
+	if storage == nil {
+		return m.store
+	}
+	return storage
+}
+
+// sameStorage reports whether storages are both nil or the same
+// pointer. Storages of other kinds are never same, because
+// they can be not comparable.
+func sameStorage(a, b Storage) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	if va.Kind() != reflect.Pointer || vb.Kind() != reflect.Pointer {
+		return false
+	}
+	return va.Type() == vb.Type() && va.Pointer() == vb.Pointer()
+}
+
+// dispatchContext is context of storage in dispatch of update.
+type dispatchContext struct {
+	storage Storage
+	ctx     Context
+	states  map[string]State // states of machines
+}
+
+// machineContext returns context of machine.
+// For default machine returns same context.
+func machineContext(c Context, machine string) Context {
+	if machine == "" {
+		return c
+	}
+	return c.Machine(machine)
+}
+
 func (hm handlerMapping) find(endpoint string, state State) (handlerEntry, bool) {
+	h, ok, _ := hm.findFunc(endpoint, func(handlerEntry) (State, error) {
+		return state, nil
+	})
+	return h, ok
+}
+
+// findFunc returns first handler for endpoint what matches state
+// of handler machine and storage. States returns by stateOf.
+func (hm handlerMapping) findFunc(
+	endpoint string,
+	stateOf func(h handlerEntry) (State, error),
+) (handlerEntry, bool, error) {
 	l := hm[endpoint]
 
 	for e := l.Front(); e != nil; e = e.Next() {
 		h := e.Value
 
-		if h.states.Has(state) || h.states.Has(AnyState) {
-			return h, true
+		if h.states.Has(AnyState) {
+			return h, true, nil
+		}
+
+		state, err := stateOf(h)
+		if err != nil {
+			return handlerEntry{}, false, err
+		}
+
+		if h.states.Has(state) {
+			return h, true, nil
 		}
 	}
 
-	return handlerEntry{}, false
+	return handlerEntry{}, false, nil
 }
 
 // ErrHandlerState indicates what manager gets error while tired
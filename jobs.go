@@ -0,0 +1,193 @@
+package fsm
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+// Data keys of scheduled jobs. Jobs what are canceled on
+// transition are stored separately, so context can cancel
+// them without manager (see Context.Set).
+const (
+	jobsKey           = "__fsm_jobs"
+	transitionJobsKey = "__fsm_jobs_transition"
+)
+
+// Job is delayed action for user. When job fires manager sets
+// target state and then calls handler by name (see HandleJob).
+// Jobs are fired by scheduler (see RunScheduler).
+//
+//	// reminder
+//	manager.HandleJob("remind", func(c tele.Context, state fsm.Context) error {
+//		return c.Send("Don't forget to finish the form")
+//	})
+//	manager.Schedule(c, time.Hour, fsm.Job{
+//		Name:               "remind",
+//		Handler:            "remind",
+//		CancelOnTransition: true,
+//	})
+//
+//	// delayed transition
+//	manager.Schedule(c, 7*24*time.Hour, fsm.Job{
+//		Name:  "trial",
+//		State: "trial@expired",
+//	})
+type Job struct {
+	// Name of job. Job replaces job of user with the same name.
+	Name string `json:"name"`
+
+	// State is target state. Empty value doesn't change state.
+	State State `json:"state,omitempty"`
+
+	// Handler is name of handler. Empty value doesn't call handler.
+	Handler string `json:"handler,omitempty"`
+
+	// CancelOnTransition cancels job when state of user changes
+	// (Context.Set with other state or Context.Finish).
+	CancelOnTransition bool `json:"cancel_on_transition,omitempty"`
+}
+
+// HandleJob registers handler of jobs by name.
+func (m *ManagerOf[C]) HandleJob(name string, h HandlerOf[C]) {
+	j := m.jobs
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.handlers[name] = h
+}
+
+// Schedule schedules job for sender of update after delay.
+// Sender resolves by DefaultAddressing.
+func (m *ManagerOf[C]) Schedule(c tele.Context, delay time.Duration, job Job) error {
+	key, ok := DefaultAddressing.Resolve(c)
+	if !ok {
+		return ErrNoTarget
+	}
+	return m.ScheduleAt(key, time.Now().Add(delay), job)
+}
+
+// ScheduleAt schedules job for key at time. Only chat,
+// user and thread of key are used.
+func (m *ManagerOf[C]) ScheduleAt(key StorageKey, at time.Time, job Job) error {
+	key = StorageKey{ChatID: key.ChatID, UserID: key.UserID, ThreadID: key.ThreadID}
+
+	e := jobEntry{Job: job, At: at}
+
+	// job with the same name can be in other registry
+	if err := m.CancelJob(key, job.Name); err != nil {
+		return err
+	}
+	if job.CancelOnTransition {
+		return m.jobs.transition.put(m.store, key, e)
+	}
+	return m.jobs.entries.put(m.store, key, e)
+}
+
+// CancelJob cancels job of key by name.
+func (m *ManagerOf[C]) CancelJob(key StorageKey, name string) error {
+	key = StorageKey{ChatID: key.ChatID, UserID: key.UserID, ThreadID: key.ThreadID}
+	if err := m.jobs.entries.remove(m.store, key, name); err != nil {
+		return err
+	}
+	return m.jobs.transition.remove(m.store, key, name)
+}
+
+func newJobs[C Context]() *jobs[C] {
+	return &jobs[C]{
+		handlers:   make(map[string]HandlerOf[C]),
+		entries:    registry[jobEntry]{dataKey: jobsKey},
+		transition: registry[jobEntry]{dataKey: transitionJobsKey},
+	}
+}
+
+// fireJobs fires expired jobs of key.
+func (m *ManagerOf[C]) fireJobs(key StorageKey, now time.Time) {
+	unlock, err := m.lockKey(key)
+	if err != nil {
+		m.bot.OnError(err, nil)
+		return
+	}
+	defer unlock()
+
+	for _, r := range []*registry[jobEntry]{&m.jobs.entries, &m.jobs.transition} {
+		expired, err := r.expired(m.store, key, now)
+		if err != nil {
+			m.bot.OnError(err, nil)
+			continue
+		}
+		for _, e := range expired {
+			m.fireJob(key, e)
+		}
+	}
+}
+
+// fireJob sets state and calls handler of job.
+// Key of job must be locked.
+func (m *ManagerOf[C]) fireJob(key StorageKey, e jobEntry) {
+	c, state := m.contextFor(key)
+	if e.State != "" {
+		if err := state.Set(e.State); err != nil {
+			m.bot.OnError(err, c)
+			return
+		}
+	}
+	if e.Handler == "" {
+		return
+	}
+
+	h, ok := m.jobs.handler(e.Handler)
+	if !ok {
+		m.bot.OnError(fmt.Errorf("fsm: handler of job %q not found", e.Handler), c)
+		return
+	}
+	if err := h(c, state); err != nil {
+		m.bot.OnError(err, c)
+	}
+}
+
+// jobs is registry of jobs.
+type jobs[C Context] struct {
+	mu       sync.Mutex // guards handlers
+	handlers map[string]HandlerOf[C]
+
+	entries    registry[jobEntry]
+	transition registry[jobEntry] // jobs with CancelOnTransition
+}
+
+func (j *jobs[C]) handler(name string) (HandlerOf[C], bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	h, ok := j.handlers[name]
+	return h, ok
+}
+
+// jobEntry is scheduled job of user.
+type jobEntry struct {
+	Job
+	At time.Time `json:"at"`
+}
+
+func (e jobEntry) id() string     { return e.Name }
+func (e jobEntry) due() time.Time { return e.At }
+
+// cancelTransitionJobs cancels jobs of sender what
+// are canceled on transition (see Job.CancelOnTransition).
+func (f *fsmContext) cancelTransitionJobs() error {
+	if f.key.Machine != "" {
+		return nil // jobs work with default machine
+	}
+
+	data := scheduleData(f.s, f.origin)
+	var entries map[string]jobEntry
+	err := data.Get(transitionJobsKey, &entries)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return data.Update(transitionJobsKey, nil)
+}
@@ -0,0 +1,262 @@
+package fsm_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vitaliy-ukiru/fsm-telebot"
+	"github.com/vitaliy-ukiru/fsm-telebot/storages/file"
+	"github.com/vitaliy-ukiru/fsm-telebot/storages/file/provider"
+	"github.com/vitaliy-ukiru/fsm-telebot/storages/memory"
+	tele "gopkg.in/telebot.v3"
+)
+
+func TestManager_Schedule(t *testing.T) {
+	bot := newTestBot(t)
+	storage := memory.NewStorage()
+	m := fsm.NewManager(bot, nil, storage, nil)
+
+	var fired []int64
+	m.HandleJob("notify", func(c tele.Context, state fsm.Context) error {
+		fired = append(fired, c.Sender().ID)
+		return nil
+	})
+
+	var (
+		trial     = fsm.StorageKey{ChatID: 1, UserID: 1}
+		moved     = fsm.StorageKey{ChatID: 2, UserID: 2}
+		cancelled = fsm.StorageKey{ChatID: 3, UserID: 3}
+		returned  = fsm.StorageKey{ChatID: 4, UserID: 4}
+		kept      = fsm.StorageKey{ChatID: 5, UserID: 5}
+		now       = time.Now()
+	)
+	require.NoError(t, storage.SetState(2, 2, "form@name"))
+	require.NoError(t, storage.SetState(4, 4, "form@name"))
+	require.NoError(t, storage.SetState(5, 5, "form@name"))
+
+	require.NoError(t, m.ScheduleAt(trial, now, fsm.Job{
+		Name:    "trial",
+		State:   "trial@expired",
+		Handler: "notify",
+	}))
+	require.NoError(t, m.ScheduleAt(moved, now, fsm.Job{
+		Name:               "remind",
+		Handler:            "notify",
+		CancelOnTransition: true,
+	}))
+	require.NoError(t, m.ScheduleAt(cancelled, now, fsm.Job{Name: "remind", Handler: "notify"}))
+	require.NoError(t, m.CancelJob(cancelled, "remind"))
+	for _, key := range []fsm.StorageKey{returned, kept} {
+		require.NoError(t, m.ScheduleAt(key, now, fsm.Job{
+			Name:               "remind",
+			Handler:            "notify",
+			CancelOnTransition: true,
+		}))
+	}
+
+	require.NoError(t, m.ContextFor(2, 2).Set("form@age"))
+
+	// A -> B -> A is transition too
+	state := m.ContextFor(4, 4)
+	require.NoError(t, state.Set("form@age"))
+	require.NoError(t, state.Set("form@name"))
+
+	// the same state isn't transition
+	require.NoError(t, m.ContextFor(5, 5).Set("form@name"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+	m.RunScheduler(ctx, 5*time.Millisecond)
+
+	assert.ElementsMatch(t, []int64{1, 5}, fired, "fired jobs")
+
+	trialState, err := storage.GetState(1, 1)
+	require.NoError(t, err)
+	assert.Equal(t, fsm.State("trial@expired"), trialState, "state of delayed transition")
+}
+
+func TestManager_ScheduleDeleteUser(t *testing.T) {
+	bot := newTestBot(t)
+	storage := memory.NewStorage()
+	m := fsm.NewManager(bot, nil, storage, nil)
+
+	m.HandleJob("notify", func(tele.Context, fsm.Context) error {
+		t.Error("job of deleted user fired")
+		return nil
+	})
+	m.Timeout("wait", 10*time.Millisecond, func(tele.Context, fsm.Context) error {
+		t.Error("timeout of deleted user fired")
+		return nil
+	})
+	m.Bind(tele.OnText, fsm.DefaultState, func(_ tele.Context, state fsm.Context) error {
+		return state.Set("wait")
+	})
+
+	bot.ProcessUpdate(textUpdate("start"))
+	require.NoError(t, m.ScheduleAt(
+		fsm.StorageKey{ChatID: 10, UserID: 20},
+		time.Now(),
+		fsm.Job{Name: "remind", Handler: "notify"},
+	))
+	require.NoError(t, storage.DeleteUser(20))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+	m.RunScheduler(ctx, 5*time.Millisecond)
+}
+
+func TestManager_ScheduleFarFirst(t *testing.T) {
+	bot := newTestBot(t)
+	m := fsm.NewManager(bot, nil, memory.NewStorage(), nil)
+
+	fired := make(chan int64, 2)
+	m.HandleJob("notify", func(c tele.Context, _ fsm.Context) error {
+		fired <- c.Sender().ID
+		return nil
+	})
+
+	// far entry is indexed first
+	now := time.Now()
+	require.NoError(t, m.ScheduleAt(fsm.StorageKey{ChatID: 1, UserID: 1}, now.Add(7*24*time.Hour), fsm.Job{
+		Name:    "trial",
+		Handler: "notify",
+	}))
+	require.NoError(t, m.ScheduleAt(fsm.StorageKey{ChatID: 2, UserID: 2}, now, fsm.Job{
+		Name:    "remind",
+		Handler: "notify",
+	}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	go m.RunScheduler(ctx, 5*time.Millisecond)
+
+	select {
+	case id := <-fired:
+		assert.Equal(t, int64(2), id, "near job fires first")
+	case <-ctx.Done():
+		t.Fatal("near job didn't fire")
+	}
+}
+
+func TestManager_ScheduleMigrateChat(t *testing.T) {
+	bot := newTestBot(t)
+	storage := memory.NewStorage()
+	m := fsm.NewManager(bot, nil, storage, nil)
+
+	fired := make(chan fsm.StorageKey, 2)
+	key := func(c tele.Context) fsm.StorageKey {
+		return fsm.StorageKey{ChatID: c.Chat().ID, UserID: c.Sender().ID}
+	}
+	m.HandleJob("notify", func(c tele.Context, _ fsm.Context) error {
+		fired <- key(c)
+		return nil
+	})
+	m.Timeout("wait", 10*time.Millisecond, func(c tele.Context, state fsm.Context) error {
+		fired <- key(c)
+		return nil
+	})
+	m.Bind(tele.OnText, fsm.DefaultState, func(_ tele.Context, state fsm.Context) error {
+		return state.Set("wait")
+	})
+
+	// timeout of chat 10 and job of chat -10
+	bot.ProcessUpdate(textUpdate("start"))
+	require.NoError(t, m.ScheduleAt(fsm.StorageKey{ChatID: -10, UserID: 20}, time.Now(), fsm.Job{
+		Name:    "trial",
+		State:   "trial@expired",
+		Handler: "notify",
+	}))
+
+	require.NoError(t, fsm.MigrateChat(storage, 10, -100))
+	require.NoError(t, fsm.MigrateChat(storage, -10, -200))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	go m.RunScheduler(ctx, 5*time.Millisecond)
+
+	var keys []fsm.StorageKey
+	for len(keys) < 2 {
+		select {
+		case key := <-fired:
+			keys = append(keys, key)
+		case <-ctx.Done():
+			t.Fatalf("schedules didn't fire, fired for %v", keys)
+		}
+	}
+	assert.ElementsMatch(t, []fsm.StorageKey{
+		{ChatID: -100, UserID: 20},
+		{ChatID: -200, UserID: 20},
+	}, keys, "keys of migrated chats")
+
+	state, err := storage.GetState(-200, 20)
+	require.NoError(t, err)
+	assert.Equal(t, fsm.State("trial@expired"), state, "state in migrated chat")
+}
+
+func TestManager_SchedulePersistent(t *testing.T) {
+	providers := []struct {
+		name string
+		p    file.Provider
+	}{
+		{name: "gob", p: provider.NewGob()},
+		{name: "json", p: provider.NewJson(provider.JsonSettings{})},
+	}
+
+	for _, tt := range providers {
+		t.Run(tt.name, func(t *testing.T) {
+			fired := make(chan string, 2)
+			newManager := func(storage fsm.Storage) (*tele.Bot, *fsm.Manager) {
+				bot := newTestBot(t)
+				m := fsm.NewManager(bot, nil, storage, nil)
+				m.Timeout("wait", 10*time.Millisecond, func(tele.Context, fsm.Context) error {
+					fired <- "timeout"
+					return nil
+				})
+				m.HandleJob("notify", func(tele.Context, fsm.Context) error {
+					fired <- "job"
+					return nil
+				})
+				m.Bind(tele.OnText, fsm.DefaultState, func(_ tele.Context, state fsm.Context) error {
+					return state.Set("wait")
+				})
+				return bot, m
+			}
+
+			// schedules are saved by first instance
+			storage := file.NewStorage(tt.p, nil)
+			bot, m := newManager(storage)
+			bot.ProcessUpdate(textUpdate("start"))
+			require.NoError(t, m.ScheduleAt(fsm.StorageKey{ChatID: 2, UserID: 2}, time.Now(), fsm.Job{
+				Name:    "remind",
+				Handler: "notify",
+			}))
+
+			var dump bytes.Buffer
+			require.NoError(t, storage.SaveTo(&dump))
+
+			// and fired by instance with reloaded storage
+			reloaded := file.NewStorage(tt.p, nil)
+			require.NoError(t, reloaded.Init(&dump))
+			_, m = newManager(reloaded)
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			go m.RunScheduler(ctx, 5*time.Millisecond)
+
+			var got []string
+			for len(got) < 2 {
+				select {
+				case event := <-fired:
+					got = append(got, event)
+				case <-ctx.Done():
+					t.Fatalf("schedules didn't fire after reload, fired %v", got)
+				}
+			}
+			assert.ElementsMatch(t, []string{"timeout", "job"}, got)
+		})
+	}
+}
@@ -0,0 +1,77 @@
+// Package locker contains implementations of fsm.Locker.
+package locker
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/vitaliy-ukiru/fsm-telebot"
+)
+
+// DefaultPollInterval is interval between attempts to lock key.
+const DefaultPollInterval = 10 * time.Millisecond
+
+// File is fsm.Locker what uses lock files in directory.
+// It works between processes on one host.
+//
+// On unix systems it uses flock(2), so locks are released by
+// system if process dies. On other systems lock file is created
+// exclusively and stale lock files must be deleted manually.
+type File struct {
+	dir string
+
+	// PollInterval is interval between attempts to lock key.
+	PollInterval time.Duration
+}
+
+var _ fsm.Locker = (*File)(nil)
+
+// NewFile returns locker what stores lock files in dir.
+// Directory will be created if it doesn't exist.
+func NewFile(dir string) (*File, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &File{dir: dir, PollInterval: DefaultPollInterval}, nil
+}
+
+// Lock implements fsm.Locker.
+func (f *File) Lock(ctx context.Context, key string) (func(), error) {
+	path := f.path(key)
+
+	var ticker *time.Ticker
+	for {
+		unlock, ok, err := tryLock(path)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return unlock, nil
+		}
+
+		if ticker == nil {
+			ticker = time.NewTicker(f.PollInterval)
+			defer ticker.Stop()
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// path returns path of lock file for key.
+func (f *File) path(key string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+			return r
+		}
+		return '_'
+	}, key)
+	return filepath.Join(f.dir, name+".lock")
+}
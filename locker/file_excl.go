@@ -0,0 +1,26 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+
+package locker
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+)
+
+// tryLock tries to lock by exclusive creating of file.
+// Lock file is deleted by unlock.
+func tryLock(path string) (unlock func(), ok bool, err error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_RDWR, 0o644)
+	if errors.Is(err, fs.ErrExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	_ = f.Close()
+
+	return func() {
+		_ = os.Remove(path)
+	}, true, nil
+}
@@ -0,0 +1,51 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+
+package locker
+
+import (
+	"os"
+	"syscall"
+)
+
+// tryLock tries to lock file by flock.
+// Lock file is deleted by unlock.
+func tryLock(path string) (unlock func(), ok bool, err error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, false, err
+	}
+
+	err = syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if err == syscall.EWOULDBLOCK {
+		_ = f.Close()
+		return nil, false, nil
+	}
+	if err != nil {
+		_ = f.Close()
+		return nil, false, err
+	}
+
+	// other process could delete file between open and lock,
+	// then we locked file what doesn't exist anymore.
+	if !sameFile(f, path) {
+		_ = f.Close()
+		return nil, false, nil
+	}
+
+	return func() {
+		_ = os.Remove(path)
+		_ = f.Close() // closing releases lock
+	}, true, nil
+}
+
+func sameFile(f *os.File, path string) bool {
+	opened, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	current, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	return os.SameFile(opened, current)
+}
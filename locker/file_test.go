@@ -0,0 +1,44 @@
+package locker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFile_Lock(t *testing.T) {
+	l, err := NewFile(t.TempDir())
+	require.NoError(t, err)
+
+	done, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	unlock, err := l.Lock(context.Background(), "10:20:0")
+	require.NoError(t, err)
+
+	_, err = l.Lock(done, "10:20:0")
+	assert.ErrorIs(t, err, context.Canceled, "try lock of locked key")
+
+	unlockOther, err := l.Lock(done, "10:21:0")
+	require.NoError(t, err, "try lock of other key")
+	unlockOther()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	locked := make(chan error)
+	go func() {
+		unlock, err := l.Lock(ctx, "10:20:0")
+		if err == nil {
+			unlock()
+		}
+		locked <- err
+	}()
+
+	time.Sleep(2 * l.PollInterval)
+	unlock()
+	assert.NoError(t, <-locked, "lock after unlock")
+}
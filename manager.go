@@ -1,27 +1,70 @@
 package fsm
 
 import (
+	"errors"
+	"fmt"
+
 	"github.com/vitaliy-ukiru/fsm-telebot/internal"
 	tele "gopkg.in/telebot.v3"
 )
 
+// HandlerOf is object for handling updates with FSM context
+// of custom type. See ManagerOf.
+type HandlerOf[C Context] func(c tele.Context, state C) error
+
 // Handler is object for handling  updates with FSM context.
-type Handler func(c tele.Context, state Context) error
+type Handler = HandlerOf[Context]
+
+// ContextMakerOf is function for create new context of custom type.
+type ContextMakerOf[C Context] func(ctx tele.Context, storage Storage) C // TODO: add error to return values
 
 // ContextMakerFunc alias for function for create new context.
 // You can use custom Context implementation.
-type ContextMakerFunc func(ctx tele.Context, storage Storage) Context // TODO: add error to return values
+type ContextMakerFunc = ContextMakerOf[Context]
 
-// Manager is object for managing FSM, binding handlers.
-type Manager struct {
+// ErrContextType is returned by handler of manager when
+// FSM context of update isn't value of context type
+// of manager (e.g. Context.Machine of custom context
+// returns other type). See ManagerOf.
+var ErrContextType = errors.New("fsm: context has wrong type")
+
+// ManagerOf is object for managing FSM, binding handlers
+// what receive FSM context of custom type.
+//
+//	type MyContext struct {
+//		fsm.Context
+//		// extra fields
+//	}
+//
+//	m := fsm.NewManagerOf(bot, nil, storage, func(c tele.Context, s fsm.Storage) *MyContext {
+//		return &MyContext{Context: fsm.NewFSMContext(c, s)}
+//	})
+//	m.Bind("/start", fsm.DefaultState, func(c tele.Context, state *MyContext) error {
+//		// no type assertions
+//	})
+//
+// For handlers with machine filter (Filter.Machine) method
+// Context.Machine of custom context must return value of C,
+// otherwise handlers return ErrContextType.
+type ManagerOf[C Context] struct {
 	bot          *tele.Bot
 	group        *tele.Group // handlers will add to group
 	store        Storage
 	handlers     handlerMapping
-	contextMaker ContextMakerFunc
+	contextMaker ContextMakerOf[C]
 	list         []tele.MiddlewareFunc
+	hooks        []TransitionHookOf[C]
+	serialize    SerializeMode
+	locker       Locker
+	dedupWindow  int
+	timeouts     *timeouts[C]
+	jobs         *jobs[C]
+	shutdown     *shutdown
 }
 
+// Manager is object for managing FSM, binding handlers.
+type Manager = ManagerOf[Context]
+
 // NewManager returns new Manger.
 func NewManager(
 	bot *tele.Bot,
@@ -29,44 +72,71 @@ func NewManager(
 	storage Storage,
 	ctxMaker ContextMakerFunc,
 ) *Manager {
+	if ctxMaker == nil {
+		ctxMaker = NewFSMContext
+	}
+	return NewManagerOf(bot, group, storage, ctxMaker)
+}
+
+// NewManagerOf returns new Manger with custom context type.
+//
+// Context maker can be nil only if C is Context,
+// in other cases it panics.
+func NewManagerOf[C Context](
+	bot *tele.Bot,
+	group *tele.Group,
+	storage Storage,
+	ctxMaker ContextMakerOf[C],
+) *ManagerOf[C] {
 	if group == nil {
 		group = bot.Group()
 	}
 	if ctxMaker == nil {
-		ctxMaker = NewFSMContext
+		maker, ok := any(ContextMakerFunc(NewFSMContext)).(ContextMakerOf[C])
+		if !ok {
+			panic("fsm: context maker is required for custom context type")
+		}
+		ctxMaker = maker
 	}
-	return &Manager{
+	return &ManagerOf[C]{
 		bot:          bot,
 		group:        group,
 		store:        storage,
 		contextMaker: ctxMaker,
 		handlers:     make(handlerMapping),
+		timeouts:     newTimeouts[C](),
+		jobs:         newJobs[C](),
+		shutdown:     &shutdown{},
 	}
 }
 
 // Group handlers for manager.
-func (m *Manager) Group() *tele.Group {
+func (m *ManagerOf[C]) Group() *tele.Group {
 	return m.group
 }
 
 // With return copy of manager with group.
 //
 // Deprecated: Incorrect behavior with separated groups.
-func (m *Manager) With(g *tele.Group) *Manager {
+// Use NewRouter instead.
+func (m *ManagerOf[C]) With(g *tele.Group) *ManagerOf[C] {
 	manager := *m
 	manager.group = g
 	return &manager
 }
 
 // SetContextMaker sets new context maker to current Manager instance.
-func (m *Manager) SetContextMaker(contextMaker ContextMakerFunc) {
+func (m *ManagerOf[C]) SetContextMaker(contextMaker ContextMakerOf[C]) {
 	m.contextMaker = contextMaker
 }
 
 // NewGroup returns manager child with copy
 // of middleware group. Adding middlewares in
 // new group doesn't affect the parent.
-func (m *Manager) NewGroup() *Manager {
+//
+// If you need separated telebot group for
+// child see NewRouter.
+func (m *ManagerOf[C]) NewGroup() *ManagerOf[C] {
 	manager := *m
 	manager.list = make([]tele.MiddlewareFunc, len(m.list))
 	copy(manager.list, m.list)
@@ -79,7 +149,7 @@ func (m *Manager) NewGroup() *Manager {
 // you can use:
 //
 //	m.Group().Use()
-func (m *Manager) Use(middlewares ...tele.MiddlewareFunc) {
+func (m *ManagerOf[C]) Use(middlewares ...tele.MiddlewareFunc) {
 	m.list = append(m.list, middlewares...)
 }
 
@@ -88,7 +158,7 @@ func (m *Manager) Use(middlewares ...tele.MiddlewareFunc) {
 // Difference between Bind and Handle methods what Handle require Filter objects.
 // And this method can work with only one state.
 // If you bind some states see docs to Handle.
-func (m *Manager) Bind(end any, state State, h Handler, middlewares ...tele.MiddlewareFunc) {
+func (m *ManagerOf[C]) Bind(end any, state State, h HandlerOf[C], middlewares ...tele.MiddlewareFunc) {
 	m.handle(end, []State{state}, h, middlewares)
 }
 
@@ -97,6 +167,9 @@ func (m *Manager) Bind(end any, state State, h Handler, middlewares ...tele.Midd
 // If you pass empty slice of states it converters to DefaultState
 // Binding some states to one handler.
 //
+// If filter targets machine (Filter.Machine) states will be checked
+// in this machine and handler receives context of machine.
+//
 //	var ( // types of variables
 //		endpoint any // string | tele.CallbackEndpoint
 //		states []State
@@ -105,26 +178,39 @@ func (m *Manager) Bind(end any, state State, h Handler, middlewares ...tele.Midd
 //	manager.Handle(fsm.F(endpoint, states...), handlerFunc)
 //	// or
 //	manager.Handle(fsm.Filter{endpoint, states}, handlerFunc)
-func (m *Manager) Handle(f Filter, h Handler, middlewares ...tele.MiddlewareFunc) {
+func (m *ManagerOf[C]) Handle(f Filter, h HandlerOf[C], middlewares ...tele.MiddlewareFunc) {
 	if len(f.States) == 0 {
 		f.States = []State{DefaultState}
 	}
 
-	m.handle(f.Endpoint, f.States, h, middlewares)
+	m.handleFilter(f, h, middlewares)
 }
 
-func (m *Manager) handle(
+func (m *ManagerOf[C]) handle(
 	end any,
 	states []State,
-	h Handler,
+	h HandlerOf[C],
 	ms []tele.MiddlewareFunc,
 ) {
-	endpoint := getEndpoint(end)
+	m.handleFilter(Filter{Endpoint: end, States: states}, h, ms)
+}
+
+func (m *ManagerOf[C]) handleFilter(f Filter, h HandlerOf[C], ms []tele.MiddlewareFunc) {
+	m.register(f, h, internal.JoinMiddlewares(m.list, ms))
+}
+
+// register adds handler with given middlewares
+// and registers endpoint in telebot group.
+func (m *ManagerOf[C]) register(f Filter, h HandlerOf[C], ms []tele.MiddlewareFunc) {
+	endpoint := getEndpoint(f.Endpoint)
+	if sameStorage(f.Storage, m.store) {
+		f.Storage = nil // nil is storage of manager in dispatch
+	}
 
 	// we handles multi handlers in telebot,
 	// so need to use middleware here
-	wrappedHandler := m.withMiddleware(m.adapter(h), ms)
-	m.handlers.add(endpoint, wrappedHandler, states)
+	wrappedHandler := withMiddleware(m.adapter(h), ms)
+	m.handlers.add(endpoint, wrappedHandler, f)
 
 	m.group.Handle(
 		endpoint,
@@ -132,11 +218,8 @@ func (m *Manager) handle(
 	)
 }
 
-// withMiddleware returns handler with join handler-scope
-// middlewares with global middlewares.
-func (m *Manager) withMiddleware(h tele.HandlerFunc, ms []tele.MiddlewareFunc) tele.HandlerFunc {
-	ms = internal.JoinMiddlewares(m.list, ms)
-
+// withMiddleware returns handler with applied middlewares.
+func withMiddleware(h tele.HandlerFunc, ms []tele.MiddlewareFunc) tele.HandlerFunc {
 	// I didn’t understand why ApplyMiddleware is called
 	// inside the handler, just copied from telebot code.
 	return func(c tele.Context) error {
@@ -150,20 +233,29 @@ func (m *Manager) withMiddleware(h tele.HandlerFunc, ms []tele.MiddlewareFunc) t
 // Used for external purposes only outside handlers chain.
 // Example: access to context without manager handlers.
 // Use only as directed and if you know what you are doing.
-func (m *Manager) HandlerAdapter(handler Handler) tele.HandlerFunc {
-	return func(c tele.Context) error {
+//
+// Handler isn't called after Shutdown.
+func (m *ManagerOf[C]) HandlerAdapter(handler HandlerOf[C]) tele.HandlerFunc {
+	return m.shutdown.guard(func(c tele.Context) error {
 		return handler(c, m.contextMaker(c, m.store))
-	}
+	})
 }
 
 // adapter wraps internal Handler to telebot.
 // difference between HandlerAdapter in support
 // wrap context.
-func (m *Manager) adapter(handler Handler) tele.HandlerFunc {
+func (m *ManagerOf[C]) adapter(handler HandlerOf[C]) tele.HandlerFunc {
 	return func(c tele.Context) error {
 		fsmCtx, ok := tryUnwrapContext(c)
 		if ok {
-			return handler(c, fsmCtx)
+			typed, ok := fsmCtx.(C)
+			if !ok {
+				// context can be of other machine or storage,
+				// new context of manager would be wrong one
+				var want C
+				return fmt.Errorf("%w: %T, want %T", ErrContextType, fsmCtx, want)
+			}
+			return handler(c, typed)
 		}
 
 		// bad case, creating new context
@@ -174,11 +266,51 @@ func (m *Manager) adapter(handler Handler) tele.HandlerFunc {
 // NewContext creates new FSM Context.
 //
 // It calls provided ContextMakerFunc.
-func (m *Manager) NewContext(teleCtx tele.Context) Context {
+func (m *ManagerOf[C]) NewContext(teleCtx tele.Context) C {
 	return m.contextMaker(teleCtx, m.store)
 }
 
+// ContextFor returns FSM context of user in chat. Context isn't
+// bound to current update, so it can be used to change state
+// or data of other user (e.g. in approve handler of admin).
+//
+//	state := m.ContextFor(req.ChatID, req.UserID)
+//	err := state.Set(approvedState)
+//
+// Context is created by context maker of manager over
+// synthesized update with chat and sender only.
+func (m *ManagerOf[C]) ContextFor(chatID, userID int64) C {
+	_, state := m.contextFor(StorageKey{ChatID: chatID, UserID: userID})
+	return state
+}
+
+// contextFor returns telebot context of synthesized update
+// for key and FSM context for it.
+//
+// Type of chat is private when chat is user, supergroup for
+// forum topics and group otherwise, so addressing by chat
+// type (e.g. strategy.ByChatType) resolves the same key.
+func (m *ManagerOf[C]) contextFor(key StorageKey) (tele.Context, C) {
+	chat := &tele.Chat{ID: key.ChatID, Type: tele.ChatGroup}
+	if key.ChatID == key.UserID {
+		chat.Type = tele.ChatPrivate
+	}
+
+	msg := &tele.Message{
+		Chat:   chat,
+		Sender: &tele.User{ID: key.UserID},
+	}
+	if key.ThreadID != 0 {
+		chat.Type = tele.ChatSuperGroup
+		msg.ThreadID = key.ThreadID
+		msg.TopicMessage = true
+	}
+
+	c := m.bot.NewContext(tele.Update{Message: msg})
+	return c, m.contextMaker(c, m.store)
+}
+
 // Storage returns manger storage instance.
-func (m *Manager) Storage() Storage {
+func (m *ManagerOf[C]) Storage() Storage {
 	return m.store
 }
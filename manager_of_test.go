@@ -0,0 +1,68 @@
+package fsm_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vitaliy-ukiru/fsm-telebot"
+	"github.com/vitaliy-ukiru/fsm-telebot/storages/memory"
+	tele "gopkg.in/telebot.v3"
+)
+
+type customContext struct {
+	fsm.Context
+	lang string
+}
+
+func TestManagerOf(t *testing.T) {
+	bot := newTestBot(t)
+	m := fsm.NewManagerOf(bot, nil, memory.NewStorage(),
+		func(c tele.Context, s fsm.Storage) *customContext {
+			return &customContext{Context: fsm.NewFSMContext(c, s), lang: "en"}
+		},
+	)
+
+	var got string
+	m.Bind(tele.OnText, fsm.DefaultState, func(c tele.Context, state *customContext) error {
+		got = state.lang
+		return nil
+	})
+	bot.ProcessUpdate(textUpdate("hello"))
+
+	assert.Equal(t, "en", got, "custom context field")
+	assert.Panics(t, func() {
+		fsm.NewManagerOf[*customContext](bot, nil, memory.NewStorage(), nil)
+	}, "nil context maker for custom type")
+}
+
+func TestManagerOf_MachineType(t *testing.T) {
+	var handleErr error
+	bot, err := tele.NewBot(tele.Settings{
+		Synchronous: true,
+		Offline:     true,
+		OnError:     func(err error, _ tele.Context) { handleErr = err },
+	})
+	require.NoError(t, err)
+
+	storage := memory.NewStorage()
+	m := fsm.NewManagerOf(bot, nil, storage,
+		func(c tele.Context, s fsm.Storage) *customContext {
+			return &customContext{Context: fsm.NewFSMContext(c, s)}
+		},
+	)
+
+	// Machine of customContext returns fsm.Context
+	m.Handle(fsm.Filter{Endpoint: tele.OnText, States: []fsm.State{fsm.DefaultState}, Machine: "cart"},
+		func(_ tele.Context, state *customContext) error {
+			t.Error("handler called with context of other machine")
+			return state.Set("cart@paid")
+		},
+	)
+	bot.ProcessUpdate(textUpdate("pay"))
+
+	assert.ErrorIs(t, handleErr, fsm.ErrContextType)
+	state, err := storage.GetState(10, 20)
+	require.NoError(t, err)
+	assert.Equal(t, fsm.DefaultState, state, "default machine")
+}
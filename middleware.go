@@ -0,0 +1,58 @@
+package fsm
+
+import (
+	tele "gopkg.in/telebot.v3"
+)
+
+// MiddlewareOf is middleware for handlers with FSM context
+// of custom type. See Middleware.
+type MiddlewareOf[C Context] func(next HandlerOf[C]) HandlerOf[C]
+
+// Middleware is middleware for handlers with FSM context.
+// It receives the same context as handler, so it can read
+// and change state before or after handler.
+//
+//	func OnlyAdmins(next fsm.Handler) fsm.Handler {
+//		return func(c tele.Context, state fsm.Context) error {
+//			if !isAdmin(c.Sender()) {
+//				return state.Finish(true)
+//			}
+//			return next(c, state)
+//		}
+//	}
+//
+//	manager.UseFSM(OnlyAdmins)
+//	// or for one handler
+//	manager.Bind("/ban", fsm.AnyState, onBan, manager.MiddlewareAdapter(OnlyAdmins))
+type Middleware = MiddlewareOf[Context]
+
+// UseFSM adds FSM middlewares to group. See Middleware and Use.
+func (m *ManagerOf[C]) UseFSM(middlewares ...MiddlewareOf[C]) {
+	m.Use(m.adaptMiddlewares(middlewares)...)
+}
+
+// MiddlewareAdapter converts FSM middleware to telebot middleware.
+// Next handler receives context what passed by middleware.
+//
+// It useful for middlewares of one handler (see Bind and Handle).
+// Middleware isn't called after Shutdown.
+func (m *ManagerOf[C]) MiddlewareAdapter(mw MiddlewareOf[C]) tele.MiddlewareFunc {
+	return func(next tele.HandlerFunc) tele.HandlerFunc {
+		return m.shutdown.guard(m.adapter(mw(func(c tele.Context, state C) error {
+			return next(WithContext(c, state))
+		})))
+	}
+}
+
+// UseFSM adds FSM middlewares to router. See ManagerOf.UseFSM.
+func (r *RouterOf[C]) UseFSM(middlewares ...MiddlewareOf[C]) {
+	r.Use(r.m.adaptMiddlewares(middlewares)...)
+}
+
+func (m *ManagerOf[C]) adaptMiddlewares(middlewares []MiddlewareOf[C]) []tele.MiddlewareFunc {
+	list := make([]tele.MiddlewareFunc, len(middlewares))
+	for i, mw := range middlewares {
+		list[i] = m.MiddlewareAdapter(mw)
+	}
+	return list
+}
@@ -0,0 +1,146 @@
+package middleware
+
+import (
+	"errors"
+
+	"github.com/vitaliy-ukiru/fsm-telebot"
+	tele "gopkg.in/telebot.v3"
+)
+
+// CleanupPolicy is action with record of user what left chat.
+type CleanupPolicy byte
+
+const (
+	// CleanupKeep keeps record as is.
+	CleanupKeep CleanupPolicy = iota
+
+	// CleanupReset resets state and keeps data.
+	CleanupReset
+
+	// CleanupPurge resets state and deletes data.
+	CleanupPurge
+)
+
+// Cleanup resets or purges records of users what leave
+// chats or block the bot. It handles updates:
+//
+//   - telebot.OnUserLeft: user left group.
+//   - telebot.OnChatMember: user left or was kicked from chat.
+//   - telebot.OnMyChatMember: user blocked the bot in private chat.
+//
+// If storage implements fsm.MemberRecordsStorage, Cleanup works with
+// all records of user in chat (forum topics and named machines).
+// Otherwise, only with record of default machine for (chat, user)
+// pair. Storage addressing (like strategy.Storage) is applied.
+//
+//	cleanup := middleware.NewCleanup(storage, middleware.CleanupPurge)
+//	cleanup.Policies[tele.ChatPrivate] = middleware.CleanupReset
+//
+//	bot.Handle(tele.OnUserLeft, cleanup.Handler())
+//	bot.Handle(tele.OnChatMember, cleanup.Handler())
+//	bot.Handle(tele.OnMyChatMember, cleanup.Handler())
+//	// or if you have own handlers for these updates
+//	bot.Use(cleanup.Middleware())
+type Cleanup struct {
+	Storage fsm.Storage
+
+	// Policies contains policies for chat types.
+	Policies map[tele.ChatType]CleanupPolicy
+
+	// Default is policy for chat types without policy.
+	Default CleanupPolicy
+}
+
+// NewCleanup returns cleanup with default policy.
+func NewCleanup(storage fsm.Storage, policy CleanupPolicy) *Cleanup {
+	return &Cleanup{
+		Storage:  storage,
+		Policies: make(map[tele.ChatType]CleanupPolicy),
+		Default:  policy,
+	}
+}
+
+// Handler returns handler what cleans records by update.
+func (cl *Cleanup) Handler() tele.HandlerFunc {
+	return cl.cleanup
+}
+
+// Middleware cleans records by update before next handler.
+// Other updates are passed as is.
+func (cl *Cleanup) Middleware() tele.MiddlewareFunc {
+	return func(next tele.HandlerFunc) tele.HandlerFunc {
+		return func(c tele.Context) error {
+			if err := cl.cleanup(c); err != nil {
+				return err
+			}
+			return next(c)
+		}
+	}
+}
+
+// Policy returns policy for chat type.
+func (cl *Cleanup) Policy(chatType tele.ChatType) CleanupPolicy {
+	if policy, ok := cl.Policies[chatType]; ok {
+		return policy
+	}
+	return cl.Default
+}
+
+func (cl *Cleanup) cleanup(c tele.Context) error {
+	chat, user, ok := leftMember(c)
+	if !ok {
+		return nil
+	}
+
+	switch cl.Policy(chat.Type) {
+	case CleanupReset:
+		return cl.reset(chat.ID, user, false)
+	case CleanupPurge:
+		return cl.reset(chat.ID, user, true)
+	}
+	return nil
+}
+
+// reset resets records of user in chat.
+func (cl *Cleanup) reset(chat, user int64, withData bool) error {
+	if ms, ok := cl.Storage.(fsm.MemberRecordsStorage); ok {
+		err := ms.ResetMember(chat, user, withData)
+		if !errors.Is(err, fsm.ErrNotSupported) {
+			return err
+		}
+	}
+	return cl.Storage.ResetState(chat, user, withData)
+}
+
+// leftMember returns chat and user what left it.
+func leftMember(c tele.Context) (*tele.Chat, int64, bool) {
+	if msg := c.Message(); msg != nil {
+		if msg.UserLeft == nil || msg.Chat == nil {
+			return nil, 0, false
+		}
+		return msg.Chat, msg.UserLeft.ID, true
+	}
+
+	u := c.Update()
+	switch {
+	case u.ChatMember != nil:
+		member := u.ChatMember.NewChatMember
+		if u.ChatMember.Chat == nil || member == nil || member.User == nil || !isLeft(member.Role) {
+			return nil, 0, false
+		}
+		return u.ChatMember.Chat, member.User.ID, true
+
+	case u.MyChatMember != nil:
+		// in private chat it means what user blocked the bot
+		chat, member := u.MyChatMember.Chat, u.MyChatMember.NewChatMember
+		if chat == nil || chat.Type != tele.ChatPrivate || member == nil || !isLeft(member.Role) {
+			return nil, 0, false
+		}
+		return chat, chat.ID, true
+	}
+	return nil, 0, false
+}
+
+func isLeft(role tele.MemberStatus) bool {
+	return role == tele.Left || role == tele.Kicked
+}
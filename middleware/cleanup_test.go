@@ -0,0 +1,100 @@
+package middleware
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vitaliy-ukiru/fsm-telebot"
+	"github.com/vitaliy-ukiru/fsm-telebot/storages/memory"
+	tele "gopkg.in/telebot.v3"
+)
+
+func TestCleanup(t *testing.T) {
+	var (
+		user    = &tele.User{ID: 20}
+		group   = &tele.Chat{ID: -10, Type: tele.ChatGroup}
+		private = &tele.Chat{ID: 20, Type: tele.ChatPrivate}
+	)
+
+	tests := []struct {
+		name      string
+		chat      *tele.Chat
+		update    tele.Update
+		wantState fsm.State
+		wantData  bool
+	}{
+		{
+			name:      "user left group",
+			chat:      group,
+			update:    tele.Update{Message: &tele.Message{Chat: group, UserLeft: user}},
+			wantState: fsm.DefaultState,
+		},
+		{
+			name: "chat member kicked",
+			chat: group,
+			update: tele.Update{ChatMember: &tele.ChatMemberUpdate{
+				Chat:          group,
+				NewChatMember: &tele.ChatMember{User: user, Role: tele.Kicked},
+			}},
+			wantState: fsm.DefaultState,
+		},
+		{
+			name: "chat member promoted",
+			chat: group,
+			update: tele.Update{ChatMember: &tele.ChatMemberUpdate{
+				Chat:          group,
+				NewChatMember: &tele.ChatMember{User: user, Role: tele.Administrator},
+			}},
+			wantState: "state",
+			wantData:  true,
+		},
+		{
+			name: "user blocked bot",
+			chat: private,
+			update: tele.Update{MyChatMember: &tele.ChatMemberUpdate{
+				Chat:          private,
+				Sender:        user,
+				NewChatMember: &tele.ChatMember{User: &tele.User{ID: 1}, Role: tele.Kicked},
+			}},
+			wantState: fsm.DefaultState,
+			wantData:  true, // reset policy for private chats
+		},
+		{
+			name:      "other update",
+			chat:      group,
+			update:    tele.Update{Message: &tele.Message{Chat: group, Sender: user, Text: "hi"}},
+			wantState: "state",
+			wantData:  true,
+		},
+	}
+
+	var b tele.Bot
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := memory.NewStorage()
+			keys := []fsm.StorageKey{
+				{ChatID: tt.chat.ID, UserID: user.ID},
+				{ChatID: tt.chat.ID, UserID: user.ID, ThreadID: 7, Machine: "cart"},
+			}
+			for _, key := range keys {
+				require.NoError(t, storage.SetStateByKey(key, "state"))
+				require.NoError(t, storage.UpdateDataByKey(key, "key", "value"))
+			}
+
+			cleanup := NewCleanup(storage, CleanupPurge)
+			cleanup.Policies[tele.ChatPrivate] = CleanupReset
+			require.NoError(t, cleanup.Handler()(b.NewContext(tt.update)))
+
+			for _, key := range keys {
+				state, err := storage.GetStateByKey(key)
+				require.NoError(t, err)
+				assert.Equalf(t, tt.wantState, state, "state of %+v", key)
+
+				var data string
+				err = storage.GetDataByKey(key, "key", &data)
+				assert.Equalf(t, tt.wantData, err == nil, "data exists of %+v (err: %v)", key, err)
+			}
+		})
+	}
+}
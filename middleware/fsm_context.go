@@ -6,15 +6,20 @@ import (
 )
 
 // ContextKey is key for telebot.Context storage what uses in middleware.
+//
+// Deprecated: Use fsm.FromContext for get context.
 var ContextKey = "fsm"
 
 // FSMContextMiddleware save FSM context in telebot.Context.
 // Recommend use without manager.
+//
+// Context can be got by fsm.FromContext.
 func FSMContextMiddleware(storage fsm.Storage) tele.MiddlewareFunc {
 	return func(next tele.HandlerFunc) tele.HandlerFunc {
 		return func(c tele.Context) error {
-			c.Set(ContextKey, fsm.NewFSMContext(c, storage))
-			return next(c)
+			fsmCtx := fsm.NewFSMContext(c, storage)
+			c.Set(ContextKey, fsmCtx)
+			return next(fsm.WithContext(c, fsmCtx))
 		}
 	}
 }
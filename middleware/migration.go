@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"github.com/vitaliy-ukiru/fsm-telebot"
+	tele "gopkg.in/telebot.v3"
+)
+
+// MigrateChat returns handler for telebot.OnMigration what moves
+// records of group to new supergroup chat (see fsm.MigrateChat).
+// Storage must implement fsm.ChatMigrator.
+//
+//	bot.Handle(tele.OnMigration, middleware.MigrateChat(storage))
+func MigrateChat(storage fsm.Storage) tele.HandlerFunc {
+	return func(c tele.Context) error {
+		return migrateChat(c, storage)
+	}
+}
+
+// MigrateChatMiddleware moves records of group to new supergroup
+// chat before next handler. It's useful if you have own handler
+// for telebot.OnMigration. Other updates are passed as is.
+// Storage must implement fsm.ChatMigrator.
+func MigrateChatMiddleware(storage fsm.Storage) tele.MiddlewareFunc {
+	return func(next tele.HandlerFunc) tele.HandlerFunc {
+		return func(c tele.Context) error {
+			if err := migrateChat(c, storage); err != nil {
+				return err
+			}
+			return next(c)
+		}
+	}
+}
+
+// migrateChat moves records if update is migration.
+func migrateChat(c tele.Context, storage fsm.Storage) error {
+	msg := c.Message()
+	if msg == nil || msg.MigrateTo == 0 || msg.Chat == nil {
+		return nil
+	}
+
+	return fsm.MigrateChat(storage, msg.Chat.ID, msg.MigrateTo)
+}
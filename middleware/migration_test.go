@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vitaliy-ukiru/fsm-telebot"
+	"github.com/vitaliy-ukiru/fsm-telebot/storages/memory"
+	tele "gopkg.in/telebot.v3"
+)
+
+// baseStorage hides optional capabilities of storage.
+type baseStorage struct{ fsm.Storage }
+
+func TestMigrateChat(t *testing.T) {
+	migration := tele.Update{Message: &tele.Message{
+		Chat:      &tele.Chat{ID: -10, Type: tele.ChatGroup},
+		MigrateTo: -100,
+	}}
+
+	tests := []struct {
+		name   string
+		update tele.Update
+		want   fsm.State // state of user in new chat
+	}{
+		{name: "migration", update: migration, want: "state"},
+		{name: "other update", update: tele.Update{Message: &tele.Message{
+			Chat: &tele.Chat{ID: -10},
+			Text: "hello",
+		}}, want: fsm.DefaultState},
+	}
+
+	var b tele.Bot
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, mode := range []string{"handler", "middleware"} {
+				storage := memory.NewStorage()
+				require.NoError(t, storage.SetState(-10, 20, "state"))
+
+				c := b.NewContext(tt.update)
+				var next bool
+				switch mode {
+				case "handler":
+					require.NoError(t, MigrateChat(storage)(c), mode)
+				case "middleware":
+					require.NoError(t, MigrateChatMiddleware(storage)(func(tele.Context) error {
+						next = true
+						return nil
+					})(c), mode)
+					assert.True(t, next, "next called")
+				}
+
+				state, err := storage.GetState(-100, 20)
+				require.NoError(t, err)
+				assert.Equal(t, tt.want, state, mode)
+			}
+		})
+	}
+
+	c := b.NewContext(migration)
+	assert.ErrorIs(t, MigrateChat(baseStorage{memory.NewStorage()})(c), fsm.ErrNotSupported)
+}
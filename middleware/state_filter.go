@@ -10,12 +10,19 @@ import (
 // Recommended uses in groups.
 // It can be uses if you want handle many non-fsm endpoints
 // for one state without manager.
+//
+// Chat and user resolves by fsm.DefaultAddressing.
+// Updates without target have fsm.DefaultState.
 func StateFilterMiddleware(storage fsm.Storage, want fsm.State) tele.MiddlewareFunc {
 	return func(next tele.HandlerFunc) tele.HandlerFunc {
 		return func(c tele.Context) error {
-			currentState, err := storage.GetState(c.Chat().ID, c.Sender().ID)
-			if err != nil {
-				return err
+			currentState := fsm.DefaultState
+			if key, ok := fsm.DefaultAddressing.Resolve(c); ok {
+				state, err := fsm.AsKeyStorage(storage).GetStateByKey(key)
+				if err != nil {
+					return err
+				}
+				currentState = state
 			}
 			if fsm.Is(currentState, want) {
 				return next(c)
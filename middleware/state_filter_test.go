@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vitaliy-ukiru/fsm-telebot"
+	"github.com/vitaliy-ukiru/fsm-telebot/storages/memory"
+	tele "gopkg.in/telebot.v3"
+)
+
+func TestStateFilterMiddleware_Topic(t *testing.T) {
+	storage := memory.NewStorage()
+
+	var b tele.Bot
+	c := b.NewContext(tele.Update{Message: &tele.Message{
+		Chat:         &tele.Chat{ID: -10, Type: tele.ChatSuperGroup},
+		Sender:       &tele.User{ID: 20},
+		ThreadID:     7,
+		TopicMessage: true,
+	}})
+	require.NoError(t, fsm.NewFSMContext(c, storage).Set("topic"))
+
+	var passed bool
+	next := func(tele.Context) error {
+		passed = true
+		return nil
+	}
+
+	require.NoError(t, StateFilterMiddleware(storage, "topic")(next)(c))
+	assert.True(t, passed, "state of topic")
+
+	passed = false
+	require.NoError(t, StateFilterMiddleware(storage, fsm.DefaultState)(next)(c))
+	assert.False(t, passed, "state of chat without topic")
+}
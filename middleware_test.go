@@ -0,0 +1,60 @@
+package fsm_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vitaliy-ukiru/fsm-telebot"
+	"github.com/vitaliy-ukiru/fsm-telebot/storages/memory"
+	tele "gopkg.in/telebot.v3"
+)
+
+func TestManager_Middleware(t *testing.T) {
+	bot := newTestBot(t)
+	storage := memory.NewStorage()
+	m := fsm.NewManager(bot, nil, storage, nil)
+
+	var seen []fsm.State
+	m.UseFSM(func(next fsm.Handler) fsm.Handler {
+		return func(c tele.Context, state fsm.Context) error {
+			s, err := state.State()
+			if err != nil {
+				return err
+			}
+			seen = append(seen, s)
+			return next(c, state)
+		}
+	})
+
+	var handled bool
+	m.Bind(tele.OnText, "start", func(c tele.Context, state fsm.Context) error {
+		handled = c.Get("plain") != nil
+		return nil
+	},
+		markMiddleware("plain"),
+		m.MiddlewareAdapter(func(next fsm.Handler) fsm.Handler {
+			return func(c tele.Context, state fsm.Context) error {
+				if err := next(c, state); err != nil {
+					return err
+				}
+				return state.Set("done")
+			}
+		}),
+	)
+
+	require.NoError(t, storage.SetState(10, 20, "start"))
+	bot.ProcessUpdate(textUpdate("hello"))
+
+	assert.Equal(t, []fsm.State{"start"}, seen, "state in manager middleware")
+	assert.True(t, handled, "telebot middleware applied")
+	assert.Equal(t, fsm.State("done"), currentStateOf(t, storage), "state set by middleware")
+}
+
+// currentStateOf returns state of test user or fails test.
+func currentStateOf(t *testing.T, storage fsm.Storage) fsm.State {
+	t.Helper()
+	s, err := storage.GetState(10, 20)
+	require.NoError(t, err)
+	return s
+}
@@ -63,6 +63,49 @@ func (_c *MockContext_Bot_Call) RunAndReturn(run func() *telebot.Bot) *MockConte
 	return _c
 }
 
+// ChatData provides a mock function with given fields:
+func (_m *MockContext) ChatData() Data {
+	ret := _m.Called()
+
+	var r0 Data
+	if rf, ok := ret.Get(0).(func() Data); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(Data)
+		}
+	}
+
+	return r0
+}
+
+// MockContext_ChatData_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ChatData'
+type MockContext_ChatData_Call struct {
+	*mock.Call
+}
+
+// ChatData is a helper method to define mock.On call
+func (_e *MockContext_Expecter) ChatData() *MockContext_ChatData_Call {
+	return &MockContext_ChatData_Call{Call: _e.mock.On("ChatData")}
+}
+
+func (_c *MockContext_ChatData_Call) Run(run func()) *MockContext_ChatData_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *MockContext_ChatData_Call) Return(_a0 Data) *MockContext_ChatData_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockContext_ChatData_Call) RunAndReturn(run func() Data) *MockContext_ChatData_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // Finish provides a mock function with given fields: deleteData
 func (_m *MockContext) Finish(deleteData bool) error {
 	ret := _m.Called(deleteData)
@@ -148,6 +191,93 @@ func (_c *MockContext_Get_Call) RunAndReturn(run func(string, interface{}) error
 	return _c
 }
 
+// GlobalData provides a mock function with given fields:
+func (_m *MockContext) GlobalData() Data {
+	ret := _m.Called()
+
+	var r0 Data
+	if rf, ok := ret.Get(0).(func() Data); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(Data)
+		}
+	}
+
+	return r0
+}
+
+// MockContext_GlobalData_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GlobalData'
+type MockContext_GlobalData_Call struct {
+	*mock.Call
+}
+
+// GlobalData is a helper method to define mock.On call
+func (_e *MockContext_Expecter) GlobalData() *MockContext_GlobalData_Call {
+	return &MockContext_GlobalData_Call{Call: _e.mock.On("GlobalData")}
+}
+
+func (_c *MockContext_GlobalData_Call) Run(run func()) *MockContext_GlobalData_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *MockContext_GlobalData_Call) Return(_a0 Data) *MockContext_GlobalData_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockContext_GlobalData_Call) RunAndReturn(run func() Data) *MockContext_GlobalData_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Machine provides a mock function with given fields: name
+func (_m *MockContext) Machine(name string) Context {
+	ret := _m.Called(name)
+
+	var r0 Context
+	if rf, ok := ret.Get(0).(func(string) Context); ok {
+		r0 = rf(name)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(Context)
+		}
+	}
+
+	return r0
+}
+
+// MockContext_Machine_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Machine'
+type MockContext_Machine_Call struct {
+	*mock.Call
+}
+
+// Machine is a helper method to define mock.On call
+//   - name string
+func (_e *MockContext_Expecter) Machine(name interface{}) *MockContext_Machine_Call {
+	return &MockContext_Machine_Call{Call: _e.mock.On("Machine", name)}
+}
+
+func (_c *MockContext_Machine_Call) Run(run func(name string)) *MockContext_Machine_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string))
+	})
+	return _c
+}
+
+func (_c *MockContext_Machine_Call) Return(_a0 Context) *MockContext_Machine_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockContext_Machine_Call) RunAndReturn(run func(string) Context) *MockContext_Machine_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // MustGet provides a mock function with given fields: key, to
 func (_m *MockContext) MustGet(key string, to interface{}) {
 	_m.Called(key, to)
@@ -182,6 +312,161 @@ func (_c *MockContext_MustGet_Call) RunAndReturn(run func(string, interface{}))
 	return _c
 }
 
+// NextIn provides a mock function with given fields: group
+func (_m *MockContext) NextIn(group *StateGroup) (State, error) {
+	ret := _m.Called(group)
+
+	var r0 State
+	var r1 error
+	if rf, ok := ret.Get(0).(func(*StateGroup) (State, error)); ok {
+		return rf(group)
+	}
+	if rf, ok := ret.Get(0).(func(*StateGroup) State); ok {
+		r0 = rf(group)
+	} else {
+		r0 = ret.Get(0).(State)
+	}
+
+	if rf, ok := ret.Get(1).(func(*StateGroup) error); ok {
+		r1 = rf(group)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockContext_NextIn_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'NextIn'
+type MockContext_NextIn_Call struct {
+	*mock.Call
+}
+
+// NextIn is a helper method to define mock.On call
+//   - group *StateGroup
+func (_e *MockContext_Expecter) NextIn(group interface{}) *MockContext_NextIn_Call {
+	return &MockContext_NextIn_Call{Call: _e.mock.On("NextIn", group)}
+}
+
+func (_c *MockContext_NextIn_Call) Run(run func(group *StateGroup)) *MockContext_NextIn_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(*StateGroup))
+	})
+	return _c
+}
+
+func (_c *MockContext_NextIn_Call) Return(_a0 State, _a1 error) *MockContext_NextIn_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockContext_NextIn_Call) RunAndReturn(run func(*StateGroup) (State, error)) *MockContext_NextIn_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// PrevIn provides a mock function with given fields: group
+func (_m *MockContext) PrevIn(group *StateGroup) (State, error) {
+	ret := _m.Called(group)
+
+	var r0 State
+	var r1 error
+	if rf, ok := ret.Get(0).(func(*StateGroup) (State, error)); ok {
+		return rf(group)
+	}
+	if rf, ok := ret.Get(0).(func(*StateGroup) State); ok {
+		r0 = rf(group)
+	} else {
+		r0 = ret.Get(0).(State)
+	}
+
+	if rf, ok := ret.Get(1).(func(*StateGroup) error); ok {
+		r1 = rf(group)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockContext_PrevIn_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'PrevIn'
+type MockContext_PrevIn_Call struct {
+	*mock.Call
+}
+
+// PrevIn is a helper method to define mock.On call
+//   - group *StateGroup
+func (_e *MockContext_Expecter) PrevIn(group interface{}) *MockContext_PrevIn_Call {
+	return &MockContext_PrevIn_Call{Call: _e.mock.On("PrevIn", group)}
+}
+
+func (_c *MockContext_PrevIn_Call) Run(run func(group *StateGroup)) *MockContext_PrevIn_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(*StateGroup))
+	})
+	return _c
+}
+
+func (_c *MockContext_PrevIn_Call) Return(_a0 State, _a1 error) *MockContext_PrevIn_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockContext_PrevIn_Call) RunAndReturn(run func(*StateGroup) (State, error)) *MockContext_PrevIn_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Session provides a mock function with given fields:
+func (_m *MockContext) Session() (string, error) {
+	ret := _m.Called()
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func() (string, error)); ok {
+		return rf()
+	}
+	if rf, ok := ret.Get(0).(func() string); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockContext_Session_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Session'
+type MockContext_Session_Call struct {
+	*mock.Call
+}
+
+// Session is a helper method to define mock.On call
+func (_e *MockContext_Expecter) Session() *MockContext_Session_Call {
+	return &MockContext_Session_Call{Call: _e.mock.On("Session")}
+}
+
+func (_c *MockContext_Session_Call) Run(run func()) *MockContext_Session_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *MockContext_Session_Call) Return(_a0 string, _a1 error) *MockContext_Session_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockContext_Session_Call) RunAndReturn(run func() (string, error)) *MockContext_Session_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // Set provides a mock function with given fields: state
 func (_m *MockContext) Set(state State) error {
 	ret := _m.Called(state)
@@ -318,6 +603,49 @@ func (_c *MockContext_Update_Call) RunAndReturn(run func(string, interface{}) er
 	return _c
 }
 
+// UserData provides a mock function with given fields:
+func (_m *MockContext) UserData() Data {
+	ret := _m.Called()
+
+	var r0 Data
+	if rf, ok := ret.Get(0).(func() Data); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(Data)
+		}
+	}
+
+	return r0
+}
+
+// MockContext_UserData_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'UserData'
+type MockContext_UserData_Call struct {
+	*mock.Call
+}
+
+// UserData is a helper method to define mock.On call
+func (_e *MockContext_Expecter) UserData() *MockContext_UserData_Call {
+	return &MockContext_UserData_Call{Call: _e.mock.On("UserData")}
+}
+
+func (_c *MockContext_UserData_Call) Run(run func()) *MockContext_UserData_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *MockContext_UserData_Call) Return(_a0 Data) *MockContext_UserData_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockContext_UserData_Call) RunAndReturn(run func() Data) *MockContext_UserData_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // NewMockContext creates a new instance of MockContext. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewMockContext(t interface {
@@ -0,0 +1,342 @@
+package fsm
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// scheduleMachine is reserved machine for scheduled entries of key.
+// It isn't data of user, see IsInternalMachine.
+const scheduleMachine = "__fsm_schedule"
+
+// registryBucket is time range of index bucket.
+const registryBucket = time.Minute
+
+// migrationsKey is global data key of migrated chats (see MigrateChat).
+const migrationsKey = "__fsm_migrations"
+
+// scheduled is entry of registry. Entry doesn't keep
+// key of user, because chat of key can be migrated.
+type scheduled interface {
+	id() string     // unique id of entry in key
+	due() time.Time // time when entry fires
+}
+
+// registry is persistent set of scheduled entries.
+//
+// Entries are stored per key in record of reserved machine,
+// so update of entry touches only record of its user. Keys are
+// indexed by buckets of due time in global data. Scheduler walks
+// buckets from cursor (last processed bucket) to current time.
+//
+// Nothing is cached, so several instances of bot can share
+// storage. For safe concurrent changes of the same key they
+// must share Locker (see ManagerOf.Serialize).
+type registry[E scheduled] struct {
+	dataKey string
+
+	mu sync.Mutex // serializes read-modify-write in process
+}
+
+// scheduleRef is reference to key in bucket.
+type scheduleRef struct {
+	ChatID   int64 `json:"chat_id"`
+	UserID   int64 `json:"user_id"`
+	ThreadID int   `json:"thread_id,omitempty"`
+}
+
+func (r scheduleRef) key() StorageKey {
+	return StorageKey{ChatID: r.ChatID, UserID: r.UserID, ThreadID: r.ThreadID}
+}
+
+// put adds or replaces entry of key.
+func (r *registry[E]) put(storage Storage, key StorageKey, e E) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	data := scheduleData(storage, key)
+	entries, err := r.entries(data)
+	if err != nil {
+		return err
+	}
+
+	bucket := bucketOf(e.due())
+	old, exists := entries[e.id()]
+
+	next := make(map[string]E, len(entries)+1)
+	for id, entry := range entries {
+		next[id] = entry
+	}
+	next[e.id()] = e
+	if err := r.save(data, next); err != nil {
+		return err
+	}
+
+	// key already is in bucket of entry
+	if exists && bucketOf(old.due()) == bucket {
+		return nil
+	}
+	return r.index(storage, key, bucket)
+}
+
+// remove deletes entry of key by id if it exists.
+// Reference in index is left, scheduler skips it.
+func (r *registry[E]) remove(storage Storage, key StorageKey, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	data := scheduleData(storage, key)
+	entries, err := r.entries(data)
+	if err != nil {
+		return err
+	}
+	if _, ok := entries[id]; !ok {
+		return nil
+	}
+	return r.save(data, without(entries, func(entryID string, _ E) bool {
+		return entryID == id
+	}))
+}
+
+// expired deletes and returns entries of key what are due at now.
+func (r *registry[E]) expired(storage Storage, key StorageKey, now time.Time) ([]E, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	data := scheduleData(storage, key)
+	entries, err := r.entries(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var expired []E
+	for _, e := range entries {
+		if !e.due().After(now) {
+			expired = append(expired, e)
+		}
+	}
+	if len(expired) == 0 {
+		return nil, nil
+	}
+
+	return expired, r.save(data, without(entries, func(_ string, e E) bool {
+		return !e.due().After(now)
+	}))
+}
+
+// keys returns keys what can have due entries at now.
+// Buckets before current are deleted and cursor is moved.
+// Chats of keys are resolved by migrations.
+func (r *registry[E]) keys(storage Storage, now time.Time) ([]StorageKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	global := globalData(storage)
+	cursor, ok, err := r.cursor(global)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	migrations, err := chatMigrations(global)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		keys    []StorageKey
+		seen    = make(map[StorageKey]bool)
+		current = bucketOf(now)
+	)
+	for bucket := cursor + 1; bucket <= current; bucket++ {
+		var refs []scheduleRef
+		err := global.Get(r.bucketKey(bucket), &refs)
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return keys, err
+		}
+		for _, ref := range refs {
+			key := ref.key()
+			key.ChatID = migratedChat(migrations, key.ChatID)
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+
+		// current bucket can get new entries
+		if bucket == current {
+			break
+		}
+		if len(refs) > 0 {
+			if err := global.Update(r.bucketKey(bucket), nil); err != nil {
+				return keys, err
+			}
+		}
+		if err := global.Update(r.cursorKey(), bucket); err != nil {
+			return keys, err
+		}
+	}
+	return keys, nil
+}
+
+// index adds key to bucket. Buckets what are already
+// processed are replaced by next bucket. Lock must be held.
+func (r *registry[E]) index(storage Storage, key StorageKey, bucket int64) error {
+	global := globalData(storage)
+	cursor, ok, err := r.cursor(global)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		// cursor is never ahead of current time, otherwise
+		// entries due earlier would wait for this entry
+		cursor = bucketOf(time.Now()) - 1
+		if bucket <= cursor {
+			cursor = bucket - 1
+		}
+		if err := global.Update(r.cursorKey(), cursor); err != nil {
+			return err
+		}
+	}
+	if bucket <= cursor {
+		bucket = cursor + 1
+	}
+
+	var refs []scheduleRef
+	err = global.Get(r.bucketKey(bucket), &refs)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return err
+	}
+
+	ref := scheduleRef{ChatID: key.ChatID, UserID: key.UserID, ThreadID: key.ThreadID}
+	for _, other := range refs {
+		if other == ref {
+			return nil
+		}
+	}
+
+	// new slice, because storage can keep old slice
+	next := make([]scheduleRef, 0, len(refs)+1)
+	next = append(next, refs...)
+	next = append(next, ref)
+	return global.Update(r.bucketKey(bucket), next)
+}
+
+// entries returns entries of key. Lock must be held.
+func (r *registry[E]) entries(data Data) (map[string]E, error) {
+	var entries map[string]E
+	err := data.Get(r.dataKey, &entries)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// save writes entries of key. Empty entries are deleted.
+// Lock must be held.
+func (r *registry[E]) save(data Data, entries map[string]E) error {
+	if len(entries) == 0 {
+		return data.Update(r.dataKey, nil)
+	}
+	return data.Update(r.dataKey, entries)
+}
+
+// cursor returns last processed bucket. Lock must be held.
+func (r *registry[E]) cursor(global Data) (int64, bool, error) {
+	var cursor int64
+	err := global.Get(r.cursorKey(), &cursor)
+	if errors.Is(err, ErrNotFound) {
+		return 0, false, nil
+	}
+	return cursor, err == nil, err
+}
+
+func (r *registry[E]) cursorKey() string { return r.dataKey + "/cursor" }
+
+func (r *registry[E]) bucketKey(bucket int64) string {
+	return fmt.Sprintf("%s/%d", r.dataKey, bucket)
+}
+
+func bucketOf(t time.Time) int64 {
+	return t.UnixNano() / int64(registryBucket)
+}
+
+// without returns copy of entries without matched entries.
+func without[E any](entries map[string]E, match func(id string, e E) bool) map[string]E {
+	var next map[string]E
+	for id, e := range entries {
+		if match(id, e) {
+			continue
+		}
+		if next == nil {
+			next = make(map[string]E, len(entries))
+		}
+		next[id] = e
+	}
+	return next
+}
+
+// scheduleData returns data of scheduled entries of key.
+func scheduleData(storage Storage, key StorageKey) Data {
+	return scopedData{
+		s: AsKeyStorage(UnwrapStorage(storage)),
+		key: StorageKey{
+			ChatID:   key.ChatID,
+			UserID:   key.UserID,
+			ThreadID: key.ThreadID,
+			Machine:  scheduleMachine,
+		},
+	}
+}
+
+// globalData returns global data of storage.
+func globalData(storage Storage) Data {
+	return scopedData{s: AsKeyStorage(UnwrapStorage(storage)), key: globalScope(StorageKey{})}
+}
+
+// migrationsMu serializes changes of migrations in process.
+var migrationsMu sync.Mutex
+
+// addMigration saves migration of chat for scheduled entries.
+func addMigration(storage Storage, from, to int64) error {
+	migrationsMu.Lock()
+	defer migrationsMu.Unlock()
+
+	global := globalData(storage)
+	migrations, err := chatMigrations(global)
+	if err != nil {
+		return err
+	}
+
+	// new map, because storage can keep old map
+	next := make(map[int64]int64, len(migrations)+1)
+	for chat, target := range migrations {
+		next[chat] = target
+	}
+	next[from] = to
+	return global.Update(migrationsKey, next)
+}
+
+// chatMigrations returns migrated chats.
+func chatMigrations(global Data) (map[int64]int64, error) {
+	var migrations map[int64]int64
+	err := global.Get(migrationsKey, &migrations)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+	return migrations, nil
+}
+
+// migratedChat returns current ID of chat.
+func migratedChat(migrations map[int64]int64, chat int64) int64 {
+	// bounded by count, so cycle of migrations can't hang
+	for i := 0; i < len(migrations); i++ {
+		to, ok := migrations[chat]
+		if !ok {
+			break
+		}
+		chat = to
+	}
+	return chat
+}
@@ -0,0 +1,163 @@
+package fsm
+
+import (
+	"github.com/vitaliy-ukiru/fsm-telebot/internal"
+	tele "gopkg.in/telebot.v3"
+)
+
+// RouterOf is node of handlers tree.
+//
+// Every router has own telebot group and own middlewares.
+// Child router gets copy of parent middlewares at creation.
+// Adding middlewares in router doesn't affect the parent
+// and existing children.
+//
+// Handlers of all routers are dispatched by manager from
+// one place. So routers can register the same endpoint
+// with different states, every handler gets middlewares
+// of its router only.
+type RouterOf[C Context] struct {
+	m       *ManagerOf[C]
+	group   *tele.Group
+	list    []tele.MiddlewareFunc
+	storage Storage
+}
+
+// Router is router of Manager.
+type Router = RouterOf[Context]
+
+// NewRouter returns router what inherits manager middlewares.
+func (m *ManagerOf[C]) NewRouter() *RouterOf[C] {
+	return newRouter(m, m.list, nil)
+}
+
+// NewRouter returns child router.
+// It inherits middlewares and storage of router.
+func (r *RouterOf[C]) NewRouter() *RouterOf[C] {
+	return newRouter(r.m, r.list, r.storage)
+}
+
+func newRouter[C Context](m *ManagerOf[C], inherited []tele.MiddlewareFunc, storage Storage) *RouterOf[C] {
+	list := make([]tele.MiddlewareFunc, len(inherited))
+	copy(list, inherited)
+
+	group := m.bot.Group()
+	group.Use(list...)
+	return &RouterOf[C]{m: m, group: group, list: list, storage: storage}
+}
+
+// Group returns telebot group of router. It contains
+// middlewares of router and useful for handlers
+// without FSM.
+func (r *RouterOf[C]) Group() *tele.Group {
+	return r.group
+}
+
+// SetStorage sets storage for handlers what will be added
+// to router after call. Nil value is storage of manager.
+//
+// It allows to use other addressing for all handlers
+// of router, for example per chat states:
+//
+//	poll := manager.ForGroup(PollSG)
+//	poll.SetStorage(strategy.NewStorage(storage, strategy.Chat))
+//
+// Filter.Storage has priority over storage of router.
+func (r *RouterOf[C]) SetStorage(storage Storage) {
+	r.storage = storage
+}
+
+// Storage returns storage of router.
+// Nil value is storage of manager.
+func (r *RouterOf[C]) Storage() Storage {
+	return r.storage
+}
+
+// Use adds middlewares to router and its group.
+func (r *RouterOf[C]) Use(middlewares ...tele.MiddlewareFunc) {
+	r.list = append(r.list, middlewares...)
+	r.group.Use(middlewares...)
+}
+
+// Bind adds handler with filter on state. See Manager.Bind.
+func (r *RouterOf[C]) Bind(end any, state State, h HandlerOf[C], middlewares ...tele.MiddlewareFunc) {
+	r.Handle(F(end, state), h, middlewares...)
+}
+
+// Handle adds handler with filter on states. See Manager.Handle.
+func (r *RouterOf[C]) Handle(f Filter, h HandlerOf[C], middlewares ...tele.MiddlewareFunc) {
+	if len(f.States) == 0 {
+		f.States = []State{DefaultState}
+	}
+	if f.Storage == nil {
+		f.Storage = r.storage
+	}
+
+	r.m.register(f, h, internal.JoinMiddlewares(r.list, middlewares))
+}
+
+// GroupRouterOf is router what binds handlers
+// for states of one StateGroup.
+type GroupRouterOf[C Context] struct {
+	*RouterOf[C]
+	group *StateGroup
+}
+
+// GroupRouter is group router of Manager.
+type GroupRouter = GroupRouterOf[Context]
+
+// ForGroup returns router for states of group.
+// It inherits manager middlewares.
+//
+//	sg := fsm.NewStateGroup("adm", "ban", "confirm")
+//	adm := manager.ForGroup(sg)
+//	adm.Use(AdminOnly, Logger)
+//	adm.Bind(tele.OnText, "ban", OnBanInput) // state "adm@ban"
+func (m *ManagerOf[C]) ForGroup(group *StateGroup) *GroupRouterOf[C] {
+	return &GroupRouterOf[C]{RouterOf: m.NewRouter(), group: group}
+}
+
+// ForGroup returns child router for states of group.
+func (r *RouterOf[C]) ForGroup(group *StateGroup) *GroupRouterOf[C] {
+	return &GroupRouterOf[C]{RouterOf: r.NewRouter(), group: group}
+}
+
+// StateGroup returns group of router.
+func (r *GroupRouterOf[C]) StateGroup() *StateGroup {
+	return r.group
+}
+
+// State returns state of group by name.
+// It panics if group doesn't contain this state.
+func (r *GroupRouterOf[C]) State(name string) State {
+	state := State(r.group.Prefix + "@" + name)
+	if !r.group.Contains(state) {
+		panic("fsm: state " + name + " not found in group " + r.group.Prefix)
+	}
+	return state
+}
+
+// Bind adds handler with filter on state of group by name.
+// Name "*" means any state of group.
+func (r *GroupRouterOf[C]) Bind(end any, name string, h HandlerOf[C], middlewares ...tele.MiddlewareFunc) {
+	r.Handle(end, []string{name}, h, middlewares...)
+}
+
+// Handle adds handler with filter on states of group by names.
+// Name "*" means any state of group.
+func (r *GroupRouterOf[C]) Handle(end any, names []string, h HandlerOf[C], middlewares ...tele.MiddlewareFunc) {
+	states := make([]State, 0, len(names))
+	for _, name := range names {
+		if name == string(AnyState) {
+			states = append(states, r.group.States...)
+			continue
+		}
+		states = append(states, r.State(name))
+	}
+
+	r.m.register(
+		Filter{Endpoint: end, States: states, Storage: r.storage},
+		h,
+		internal.JoinMiddlewares(r.list, middlewares),
+	)
+}
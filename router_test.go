@@ -0,0 +1,242 @@
+package fsm_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vitaliy-ukiru/fsm-telebot"
+	"github.com/vitaliy-ukiru/fsm-telebot/storages/memory"
+	"github.com/vitaliy-ukiru/fsm-telebot/storages/strategy"
+	tele "gopkg.in/telebot.v3"
+)
+
+func newTestBot(t *testing.T) *tele.Bot {
+	t.Helper()
+	bot, err := tele.NewBot(tele.Settings{
+		Synchronous: true,
+		Offline:     true,
+		OnError: func(err error, _ tele.Context) {
+			assert.NoError(t, err)
+		},
+	})
+	require.NoError(t, err)
+	return bot
+}
+
+func textUpdate(text string) tele.Update {
+	return tele.Update{Message: &tele.Message{
+		Text:   text,
+		Chat:   &tele.Chat{ID: 10},
+		Sender: &tele.User{ID: 20},
+	}}
+}
+
+func markMiddleware(key string) tele.MiddlewareFunc {
+	return func(next tele.HandlerFunc) tele.HandlerFunc {
+		return func(c tele.Context) error {
+			c.Set(key, true)
+			return next(c)
+		}
+	}
+}
+
+func TestGroupRouter(t *testing.T) {
+	bot := newTestBot(t)
+	storage := memory.NewStorage()
+	m := fsm.NewManager(bot, nil, storage, nil)
+
+	sg := fsm.NewStateGroup("adm", "ban", "confirm")
+	adm := m.ForGroup(sg)
+	adm.Use(markMiddleware("adm"))
+
+	var calls []string
+	var marked []bool
+	record := func(name string) fsm.Handler {
+		return func(c tele.Context, _ fsm.Context) error {
+			calls = append(calls, name)
+			marked = append(marked, c.Get("adm") != nil)
+			return nil
+		}
+	}
+
+	adm.Bind(tele.OnText, "ban", record("ban"))
+	adm.Bind("/cancel", "*", record("cancel"))
+	m.Bind(tele.OnText, fsm.DefaultState, record("default"))
+
+	bot.ProcessUpdate(textUpdate("hello"))
+	require.NoError(t, storage.SetState(10, 20, sg.First()))
+	bot.ProcessUpdate(textUpdate("hello"))
+	require.NoError(t, storage.SetState(10, 20, sg.Last()))
+	bot.ProcessUpdate(textUpdate("/cancel"))
+
+	assert.Equal(t, []string{"default", "ban", "cancel"}, calls, "called handlers")
+	assert.Equal(t, []bool{false, true, true}, marked, "router middleware applied")
+
+	assert.Panics(t, func() { adm.State("unknown") }, "unknown state of group")
+}
+
+func TestRouter_SameEndpoint(t *testing.T) {
+	bot := newTestBot(t)
+	storage := memory.NewStorage()
+	m := fsm.NewManager(bot, nil, storage, nil)
+	m.Use(markMiddleware("root"))
+
+	first := m.NewRouter()
+	first.Use(markMiddleware("first"))
+	second := first.NewRouter()
+	second.Use(markMiddleware("second"))
+
+	type call struct {
+		name       string
+		root, f, s bool
+	}
+	var calls []call
+	record := func(name string) fsm.Handler {
+		return func(c tele.Context, _ fsm.Context) error {
+			calls = append(calls, call{
+				name: name,
+				root: c.Get("root") != nil,
+				f:    c.Get("first") != nil,
+				s:    c.Get("second") != nil,
+			})
+			return nil
+		}
+	}
+
+	first.Bind(tele.OnText, "first", record("first"))
+	second.Bind(tele.OnText, "second", record("second"))
+	second.Group().Handle("/plain", func(c tele.Context) error {
+		calls = append(calls, call{
+			name: "plain",
+			root: c.Get("root") != nil,
+			f:    c.Get("first") != nil,
+			s:    c.Get("second") != nil,
+		})
+		return nil
+	})
+
+	require.NoError(t, storage.SetState(10, 20, "first"))
+	bot.ProcessUpdate(textUpdate("hello"))
+	require.NoError(t, storage.SetState(10, 20, "second"))
+	bot.ProcessUpdate(textUpdate("hello"))
+	bot.ProcessUpdate(textUpdate("/plain"))
+
+	assert.Equal(t, []call{
+		{name: "first", root: true, f: true},
+		{name: "second", root: true, f: true, s: true},
+		{name: "plain", root: true, f: true, s: true},
+	}, calls)
+}
+
+func TestRouter_SetStorage(t *testing.T) {
+	bot := newTestBot(t)
+	storage := memory.NewStorage()
+	m := fsm.NewManager(bot, nil, storage, nil)
+
+	sg := fsm.NewStateGroup("poll", "question")
+	poll := m.ForGroup(sg)
+	poll.SetStorage(strategy.NewStorage(storage, strategy.Chat))
+
+	var calls []string
+	m.Bind("/poll", fsm.DefaultState, func(c tele.Context, _ fsm.Context) error {
+		calls = append(calls, "start")
+		return strategy.NewStorage(storage, strategy.Chat).SetState(10, 20, sg.First())
+	})
+	poll.Bind(tele.OnText, "question", func(c tele.Context, state fsm.Context) error {
+		calls = append(calls, "question")
+		return state.Finish(true)
+	})
+	m.Bind(tele.OnText, fsm.DefaultState, func(c tele.Context, _ fsm.Context) error {
+		calls = append(calls, "personal")
+		return nil
+	})
+
+	bot.ProcessUpdate(textUpdate("/poll"))
+
+	// other user of chat answers to poll of chat
+	other := textUpdate("answer")
+	other.Message.Sender = &tele.User{ID: 21}
+	bot.ProcessUpdate(other)
+	bot.ProcessUpdate(textUpdate("hello"))
+
+	assert.Equal(t, []string{"start", "question", "personal"}, calls)
+
+	state, err := storage.GetState(10, 0)
+	require.NoError(t, err)
+	assert.Equal(t, fsm.DefaultState, state, "chat state after finish")
+}
+
+// valueStorage is storage of value type what isn't comparable.
+type valueStorage struct {
+	*memory.Storage
+	tags map[string]string
+}
+
+func TestRouter_SetStorageValue(t *testing.T) {
+	bot := newTestBot(t)
+	storage := memory.NewStorage()
+	m := fsm.NewManager(bot, nil, valueStorage{Storage: storage}, nil)
+
+	router := m.NewRouter()
+	router.SetStorage(valueStorage{Storage: storage})
+
+	var calls []string
+	m.Bind(tele.OnText, "form", func(c tele.Context, _ fsm.Context) error {
+		calls = append(calls, "manager")
+		return nil
+	})
+	router.Bind(tele.OnText, fsm.AnyState, func(c tele.Context, _ fsm.Context) error {
+		calls = append(calls, "router")
+		return nil
+	})
+
+	assert.NotPanics(t, func() { bot.ProcessUpdate(textUpdate("hello")) })
+	assert.Equal(t, []string{"router"}, calls)
+}
+
+// readsStorage is storage of value type what counts reads of states.
+type readsStorage struct {
+	*memory.Storage
+	reads *int32
+}
+
+func (s readsStorage) GetStateByKey(key fsm.StorageKey) (fsm.State, error) {
+	atomic.AddInt32(s.reads, 1)
+	return s.Storage.GetStateByKey(key)
+}
+
+func TestManager_ValueStorage(t *testing.T) {
+	bot := newTestBot(t)
+	storage := readsStorage{Storage: memory.NewStorage(), reads: new(int32)}
+	m := fsm.NewManager(bot, nil, storage, nil)
+
+	fired := make(chan struct{}, 1)
+	m.Timeout("wait", 10*time.Millisecond, func(tele.Context, fsm.Context) error {
+		fired <- struct{}{}
+		return nil
+	})
+	m.Bind(tele.OnText, "form", func(tele.Context, fsm.Context) error {
+		t.Error("handler of other state called")
+		return nil
+	})
+	m.Bind(tele.OnText, fsm.DefaultState, func(_ tele.Context, state fsm.Context) error {
+		assert.Equal(t, int32(1), atomic.LoadInt32(storage.reads), "state is read once in dispatch")
+		return state.Set("wait")
+	})
+
+	bot.ProcessUpdate(textUpdate("start"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	go m.RunScheduler(ctx, 5*time.Millisecond)
+
+	select {
+	case <-fired:
+	case <-ctx.Done():
+		t.Fatal("timeout of value storage didn't fire")
+	}
+}
@@ -0,0 +1,76 @@
+package fsm
+
+import (
+	"context"
+	"time"
+)
+
+// DefaultSchedulerInterval is interval of scheduler checks.
+const DefaultSchedulerInterval = time.Second
+
+// RunScheduler runs scheduler of manager what fires timeouts
+// and jobs (see Timeout and Schedule). It blocks until ctx
+// will be done.
+//
+//	go manager.RunScheduler(ctx, 0)
+//
+// Zero or negative interval is DefaultSchedulerInterval.
+// Errors of handlers are passed to telebot.Bot.OnError.
+func (m *ManagerOf[C]) RunScheduler(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultSchedulerInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		m.tick(time.Now())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// tick fires all expired events.
+func (m *ManagerOf[C]) tick(now time.Time) {
+	if !m.shutdown.enter() {
+		return
+	}
+	defer m.shutdown.leave()
+
+	if m.timeouts != nil {
+		keys, err := m.timeouts.deadlines.keys(m.store, now)
+		if err != nil {
+			m.bot.OnError(err, nil)
+		}
+		for _, key := range keys {
+			m.fireTimeouts(key, now)
+		}
+	}
+
+	if m.jobs != nil {
+		keys, err := m.jobs.entries.keys(m.store, now)
+		if err != nil {
+			m.bot.OnError(err, nil)
+		}
+		transitionKeys, err := m.jobs.transition.keys(m.store, now)
+		if err != nil {
+			m.bot.OnError(err, nil)
+		}
+		for _, key := range append(keys, transitionKeys...) {
+			m.fireJobs(key, now)
+		}
+	}
+}
+
+// lockKey locks key for scheduled event if serialization is on.
+func (m *ManagerOf[C]) lockKey(key StorageKey) (func(), error) {
+	if m.serialize == SerializeOff {
+		return func() {}, nil
+	}
+	return m.locker.Lock(context.Background(), lockKey(key))
+}
@@ -0,0 +1,180 @@
+package fsm
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+// Locker locks keys of updates while they are being processed.
+// Manager uses it for serialization (see ManagerOf.Serialize).
+//
+// Default Locker works in one process (see NewLocalLocker).
+// If you run several instances of bot, use locker what works
+// between them, for example locker.File for one host.
+type Locker interface {
+	// Lock locks key. It waits until key will be unlocked or
+	// ctx will be done, then returns ctx.Err().
+	//
+	// Implementation must try to lock at least once even if
+	// ctx is already done. So done context means "try lock".
+	Lock(ctx context.Context, key string) (unlock func(), err error)
+}
+
+// SerializeMode is mode of processing updates with the same
+// storage key. See ManagerOf.Serialize.
+type SerializeMode byte
+
+const (
+	// SerializeOff processes updates concurrently (telebot behavior).
+	SerializeOff SerializeMode = iota
+
+	// SerializeWait waits until previous update of key will be processed.
+	SerializeWait
+
+	// SerializeDrop drops update if previous update of key
+	// is being processed.
+	SerializeDrop
+)
+
+// Serialize sets mode of processing updates of one key
+// (chat, user and thread, see DefaultAddressing).
+// Keys are locked by Locker of manager (see SetLocker).
+//
+// If telebot works not in synchronous mode, updates of one
+// user can be processed in parallel. For example, user
+// double-taps a button and two handlers run for the same
+// state. Serialization prevents it.
+//
+//	manager.Serialize(fsm.SerializeDrop)
+func (m *ManagerOf[C]) Serialize(mode SerializeMode) {
+	m.serialize = mode
+	if m.locker == nil {
+		m.locker = NewLocalLocker()
+	}
+}
+
+// SetLocker sets locker for serialization of updates.
+// Nil value is local locker (see NewLocalLocker).
+// Locker works only if serialization is on.
+//
+//	l, err := locker.NewFile("/run/bot/locks")
+//	// handle error
+//	manager.SetLocker(l)
+//	manager.Serialize(fsm.SerializeWait)
+func (m *ManagerOf[C]) SetLocker(locker Locker) {
+	if locker == nil {
+		locker = NewLocalLocker()
+	}
+	m.locker = locker
+}
+
+// doneContext is context for try lock.
+var doneContext = func() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	return ctx
+}()
+
+// lockUpdate locks key of update by serialize mode.
+// If update must be dropped returns false.
+func (m *ManagerOf[C]) lockUpdate(c tele.Context) (unlock func(), ok bool, err error) {
+	if m.serialize == SerializeOff {
+		return func() {}, true, nil
+	}
+
+	key, ok := DefaultAddressing.Resolve(c)
+	if !ok {
+		return func() {}, true, nil
+	}
+
+	ctx := context.Background()
+	if m.serialize == SerializeDrop {
+		ctx = doneContext
+	}
+
+	unlock, err = m.locker.Lock(ctx, lockKey(key))
+	if errors.Is(err, context.Canceled) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return unlock, true, nil
+}
+
+// lockKey returns string representation of chat, user and thread.
+func lockKey(key StorageKey) string {
+	return strconv.FormatInt(key.ChatID, 10) + ":" +
+		strconv.FormatInt(key.UserID, 10) + ":" +
+		strconv.Itoa(key.ThreadID)
+}
+
+// localLocker is set of mutexes by key.
+// Entry of key exists only while somebody holds or waits it.
+type localLocker struct {
+	mu      sync.Mutex
+	entries map[string]*keyedEntry
+}
+
+type keyedEntry struct {
+	ch   chan struct{} // ch works as mutex
+	refs int           // count of holders and waiters
+}
+
+// NewLocalLocker returns Locker what works in one process.
+func NewLocalLocker() Locker {
+	return newLocalLocker()
+}
+
+func newLocalLocker() *localLocker {
+	return &localLocker{entries: make(map[string]*keyedEntry)}
+}
+
+func (k *localLocker) Lock(ctx context.Context, key string) (func(), error) {
+	e := k.acquire(key)
+
+	select {
+	case e.ch <- struct{}{}:
+	default:
+		select {
+		case e.ch <- struct{}{}:
+		case <-ctx.Done():
+			k.release(key, e)
+			return nil, ctx.Err()
+		}
+	}
+
+	return func() {
+		<-e.ch
+		k.release(key, e)
+	}, nil
+}
+
+// acquire returns entry of key and increments references.
+func (k *localLocker) acquire(key string) *keyedEntry {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	e, ok := k.entries[key]
+	if !ok {
+		e = &keyedEntry{ch: make(chan struct{}, 1)}
+		k.entries[key] = e
+	}
+	e.refs++
+	return e
+}
+
+// release decrements references and deletes unused entry.
+func (k *localLocker) release(key string, e *keyedEntry) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	e.refs--
+	if e.refs == 0 {
+		delete(k.entries, key)
+	}
+}
@@ -0,0 +1,93 @@
+package fsm
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	tele "gopkg.in/telebot.v3"
+)
+
+func Test_localLocker(t *testing.T) {
+	k := newLocalLocker()
+	ctx := context.Background()
+
+	unlock, err := k.Lock(ctx, "a")
+	require.NoError(t, err)
+
+	_, err = k.Lock(doneContext, "a")
+	assert.ErrorIs(t, err, context.Canceled, "try lock of locked key")
+
+	unlockB, err := k.Lock(doneContext, "b")
+	require.NoError(t, err, "try lock of other key")
+	unlockB()
+
+	locked := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		unlock, _ := k.Lock(ctx, "a")
+		close(locked)
+		unlock()
+		close(done)
+	}()
+
+	select {
+	case <-locked:
+		t.Fatal("lock of locked key doesn't wait")
+	default:
+	}
+	unlock()
+	<-done
+
+	assert.Empty(t, k.entries, "entries after unlock")
+}
+
+func TestManager_Serialize(t *testing.T) {
+	bot, err := tele.NewBot(tele.Settings{Synchronous: true, Offline: true})
+	require.NoError(t, err)
+	m := NewManager(bot, nil, nil, func(c tele.Context, _ Storage) Context {
+		return &MockContext{}
+	})
+	m.Serialize(SerializeDrop)
+
+	var (
+		mu      sync.Mutex
+		calls   int
+		started = make(chan struct{})
+		release = make(chan struct{})
+	)
+	m.Bind(tele.OnText, AnyState, func(c tele.Context, _ Context) error {
+		mu.Lock()
+		calls++
+		mu.Unlock()
+		if c.Text() == "slow" {
+			close(started)
+			<-release
+		}
+		return nil
+	})
+
+	update := func(text string) tele.Update {
+		return tele.Update{Message: &tele.Message{
+			Text:   text,
+			Chat:   &tele.Chat{ID: 10},
+			Sender: &tele.User{ID: 20},
+		}}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		bot.ProcessUpdate(update("slow"))
+		close(done)
+	}()
+	<-started
+	bot.ProcessUpdate(update("fast")) // dropped
+	close(release)
+	<-done
+	bot.ProcessUpdate(update("fast"))
+
+	assert.Equal(t, 2, calls, "handler calls")
+	assert.Empty(t, m.locker.(*localLocker).entries, "entries after processing")
+}
@@ -0,0 +1,54 @@
+package fsm
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+)
+
+// sessionKey is data key of session id.
+const sessionKey = "__fsm_session"
+
+// newSessionID returns random session id.
+func newSessionID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func (f *fsmContext) Session() (string, error) {
+	if f.noTarget {
+		return "", nil
+	}
+
+	// id is left if state was reset by storage directly
+	state, err := f.State()
+	if err != nil || state == DefaultState {
+		return "", err
+	}
+
+	var id string
+	err = f.s.GetDataByKey(f.key, sessionKey, &id)
+	if errors.Is(err, ErrNotFound) {
+		return "", nil
+	}
+	return id, err
+}
+
+// updateSession starts new session if sender enters flow
+// from DefaultState and ends it if sender returns to it.
+func (f *fsmContext) updateSession(current, next State) error {
+	switch {
+	case next == DefaultState:
+		return f.s.UpdateDataByKey(f.key, sessionKey, nil)
+	case current == DefaultState:
+		id, err := newSessionID()
+		if err != nil {
+			return err
+		}
+		return f.s.UpdateDataByKey(f.key, sessionKey, id)
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package fsm
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+// Shutdown gracefully stops manager. It stops accepting new
+// updates and scheduled events (see RunScheduler), waits for
+// in-flight handlers and closes storage.
+//
+//	go bot.Stop()
+//	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+//	defer cancel()
+//	if err := m.Shutdown(ctx); err != nil {
+//		log.Print(err)
+//	}
+//
+// Updates received after shutdown are dropped. It covers handlers
+// of manager and routers, HandlerAdapter (and TelebotHandlerForState)
+// and FSM middlewares (see MiddlewareAdapter). Telebot handlers
+// and middlewares what don't use manager aren't tracked.
+//
+// If ctx is done before handlers finish, storage isn't closed
+// and Shutdown returns error of ctx. Shutdown can be called
+// again to continue waiting. Storage is closed only once.
+func (m *ManagerOf[C]) Shutdown(ctx context.Context) error {
+	s := m.shutdown
+	if s == nil {
+		return m.store.Close()
+	}
+
+	select {
+	case <-s.stop():
+	case <-ctx.Done():
+		return fmt.Errorf("fsm: wait handlers: %w", ctx.Err())
+	}
+
+	select {
+	case <-s.close(m.store):
+		if s.closeErr != nil {
+			return fmt.Errorf("fsm: close storage: %w", s.closeErr)
+		}
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("fsm: close storage: %w", ctx.Err())
+	}
+}
+
+// shutdown tracks in-flight handlers of manager.
+type shutdown struct {
+	mu      sync.Mutex
+	stopped bool
+	wg      sync.WaitGroup
+
+	closeOnce sync.Once
+	closed    chan struct{}
+	closeErr  error
+}
+
+// enter registers handler. It returns false if manager is stopped.
+func (s *shutdown) enter() bool {
+	if s == nil {
+		return true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stopped {
+		return false
+	}
+	s.wg.Add(1)
+	return true
+}
+
+func (s *shutdown) leave() {
+	if s != nil {
+		s.wg.Done()
+	}
+}
+
+// guard wraps handler with enter and leave.
+// Handler isn't called if manager is stopped.
+//
+// Handlers inside dispatch of manager aren't guarded again:
+// dispatch already has entered, so they must run
+// even if Shutdown starts.
+func (s *shutdown) guard(h tele.HandlerFunc) tele.HandlerFunc {
+	return func(c tele.Context) error {
+		if isDispatched(c) {
+			return h(c)
+		}
+		if !s.enter() {
+			return nil
+		}
+		defer s.leave()
+		return h(c)
+	}
+}
+
+// stop stops accepting handlers and returns channel
+// what is closed after all handlers leave.
+func (s *shutdown) stop() <-chan struct{} {
+	s.mu.Lock()
+	s.stopped = true
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	return done
+}
+
+// close closes storage once and returns channel what
+// is closed after it. Error is saved to closeErr.
+func (s *shutdown) close(storage Storage) <-chan struct{} {
+	s.closeOnce.Do(func() {
+		s.closed = make(chan struct{})
+		go func() {
+			s.closeErr = storage.Close()
+			close(s.closed)
+		}()
+	})
+	return s.closed
+}
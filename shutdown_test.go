@@ -0,0 +1,157 @@
+package fsm_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vitaliy-ukiru/fsm-telebot"
+	"github.com/vitaliy-ukiru/fsm-telebot/storages/memory"
+	tele "gopkg.in/telebot.v3"
+)
+
+type closeCounter struct {
+	*memory.Storage
+	closed int32
+}
+
+func (s *closeCounter) Close() error {
+	atomic.AddInt32(&s.closed, 1)
+	return s.Storage.Close()
+}
+
+func TestManager_Shutdown(t *testing.T) {
+	bot := newTestBot(t)
+	storage := &closeCounter{Storage: memory.NewStorage()}
+	m := fsm.NewManager(bot, nil, storage, nil)
+
+	var (
+		handled int32
+		entered = make(chan struct{})
+		release = make(chan struct{})
+	)
+	m.Bind(tele.OnText, fsm.AnyState, func(c tele.Context, state fsm.Context) error {
+		if c.Text() == "slow" {
+			close(entered)
+			<-release
+		}
+		atomic.AddInt32(&handled, 1)
+		return state.Set("done")
+	})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		bot.ProcessUpdate(textUpdate("slow"))
+	}()
+	<-entered
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := m.Shutdown(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded, "handler in flight")
+	assert.Zero(t, atomic.LoadInt32(&storage.closed), "storage closed while handler works")
+
+	bot.ProcessUpdate(textUpdate("late"))
+
+	close(release)
+	<-done
+
+	require.NoError(t, m.Shutdown(context.Background()))
+	require.NoError(t, m.Shutdown(context.Background()))
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&handled), "updates after shutdown are dropped")
+	assert.Equal(t, int32(1), atomic.LoadInt32(&storage.closed), "storage closed once")
+	assert.Equal(t, fsm.State("done"), currentStateOf(t, storage), "write of in-flight handler")
+}
+
+func TestManager_ShutdownAdapters(t *testing.T) {
+	bot := newTestBot(t)
+	m := fsm.NewManager(bot, nil, memory.NewStorage(), nil)
+
+	var (
+		entered = make(chan struct{})
+		release = make(chan struct{})
+	)
+	adapted := m.HandlerAdapter(func(tele.Context, fsm.Context) error {
+		close(entered)
+		<-release
+		return nil
+	})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = adapted(bot.NewContext(textUpdate("slow")))
+	}()
+	<-entered
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, m.Shutdown(ctx), context.DeadlineExceeded, "adapted handler in flight")
+
+	close(release)
+	<-done
+	require.NoError(t, m.Shutdown(context.Background()))
+
+	called := func(tele.Context, fsm.Context) error {
+		t.Error("handler called after shutdown")
+		return nil
+	}
+	c := bot.NewContext(textUpdate("late"))
+	assert.NoError(t, m.HandlerAdapter(called)(c))
+	assert.NoError(t, m.TelebotHandlerForState(fsm.AnyState, called)(c))
+
+	mw := m.MiddlewareAdapter(func(next fsm.Handler) fsm.Handler {
+		return func(c tele.Context, state fsm.Context) error {
+			t.Error("middleware called after shutdown")
+			return next(c, state)
+		}
+	})
+	assert.NoError(t, mw(func(tele.Context) error { return nil })(c))
+}
+
+func TestManager_ShutdownInMiddleware(t *testing.T) {
+	bot := newTestBot(t)
+	m := fsm.NewManager(bot, nil, memory.NewStorage(), nil)
+
+	var (
+		handled int32
+		entered = make(chan struct{})
+		release = make(chan struct{})
+	)
+	m.Use(func(next tele.HandlerFunc) tele.HandlerFunc {
+		return func(c tele.Context) error {
+			close(entered)
+			<-release
+			return next(c)
+		}
+	})
+	m.UseFSM(func(next fsm.Handler) fsm.Handler {
+		return next
+	})
+	m.Bind(tele.OnText, fsm.AnyState, func(tele.Context, fsm.Context) error {
+		atomic.AddInt32(&handled, 1)
+		return nil
+	})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		bot.ProcessUpdate(textUpdate("slow"))
+	}()
+	<-entered
+
+	// shutdown starts while update is in middlewares
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, m.Shutdown(ctx), context.DeadlineExceeded, "update in flight")
+
+	close(release)
+	<-done
+	require.NoError(t, m.Shutdown(context.Background()))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&handled), "in-flight handler")
+}
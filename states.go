@@ -1,5 +1,18 @@
 package fsm
 
+import (
+	"errors"
+	"strings"
+	"sync"
+)
+
+// ErrStateNotInGroup returns if current state is not in required StateGroup.
+var ErrStateNotInGroup = errors.New("fsm: state is not in group")
+
+// ErrStateChanged returns if state was changed by other
+// update while context moved it (see Context.NextIn).
+var ErrStateChanged = errors.New("fsm: state was changed concurrently")
+
 // State objects just string for identification.
 //
 // Default state is empty string.
@@ -22,6 +35,19 @@ func (s State) GoString() string {
 	}
 }
 
+// Parse splits state into group prefix and state name.
+// For state without group returns empty prefix and full state as name.
+//
+//	fsm.State("reg@name").Parse() // "reg", "name"
+//	fsm.State("my_state").Parse() // "", "my_state"
+func (s State) Parse() (group string, name string) {
+	group, name, ok := strings.Cut(string(s), "@")
+	if !ok {
+		return "", string(s)
+	}
+	return group, name
+}
+
 // Is indicates what state corresponds for other state.
 func Is(s State, other State) bool {
 	// if current or other state is * => every state equal
@@ -48,6 +74,8 @@ func ContainsState(s State, other ...State) bool {
 type StateGroup struct {
 	Prefix string
 	States []State
+
+	keys []string // associated data keys, see StateGroup.Key
 }
 
 // NewStateGroup returns new StateGroup.
@@ -68,6 +96,81 @@ func (s *StateGroup) New(name string) (state State) {
 	return
 }
 
+// Key returns data key with group prefix and associates it with group.
+//
+// When sender leaves group (Context.Set with state of other group
+// or Context.Finish) data by associated keys will be deleted.
+// Other data stays intact.
+//
+//	sg := fsm.NewStateGroup("reg", "name")
+//	state.Update(sg.Key("name"), name) // key is "reg@name"
+//
+// Keys are known only after association, so call Key (or WithKeys)
+// on group initialization, not in handlers. Otherwise, after restart
+// data can stay when sender leaves group.
+//
+// Associations are registered globally for the whole process and
+// shared by all managers. Groups with the same prefix share keys.
+func (s *StateGroup) Key(name string) string {
+	key := s.Prefix + "@" + name
+
+	groups.l.Lock()
+	defer groups.l.Unlock()
+	if !containsKey(s.keys, key) {
+		s.keys = append(s.keys, key)
+	}
+	groups.add(s)
+	return key
+}
+
+// Keys returns data keys associated with group. See StateGroup.Key.
+func (s *StateGroup) Keys() []string {
+	groups.l.RLock()
+	defer groups.l.RUnlock()
+
+	keys := make([]string, len(s.keys))
+	copy(keys, s.keys)
+	return keys
+}
+
+// WithKeys associates data keys with group and returns it.
+// See StateGroup.Key.
+//
+//	var sg = fsm.NewStateGroup("reg", "name", "age").WithKeys("name", "age")
+func (s *StateGroup) WithKeys(names ...string) *StateGroup {
+	for _, name := range names {
+		s.Key(name)
+	}
+	return s
+}
+
+// Contains indicates what state is in group.
+func (s *StateGroup) Contains(state State) bool {
+	return s.Index(state) != -1
+}
+
+// Index returns the index of state in group.
+// Returns -1 if state is not found.
+func (s *StateGroup) Index(state State) int {
+	return stateIndex(s.States, state)
+}
+
+// First state of group. Returns default state if group is empty.
+func (s *StateGroup) First() State {
+	if len(s.States) == 0 {
+		return DefaultState
+	}
+	return s.States[0]
+}
+
+// Last state of group. Returns default state if group is empty.
+func (s *StateGroup) Last() State {
+	if len(s.States) == 0 {
+		return DefaultState
+	}
+	return s.States[len(s.States)-1]
+}
+
 // Previous state relative to current.
 // Returns default state if current state is first or not found.
 func (s *StateGroup) Previous(current State) State {
@@ -98,3 +201,58 @@ func stateIndex(states []State, s State) int {
 	}
 	return -1
 }
+
+func containsKey(keys []string, key string) bool {
+	for _, k := range keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// groups contains groups with associated data keys by prefix.
+// Several groups can have the same prefix.
+var groups = groupRegistry{m: make(map[string][]*StateGroup)}
+
+type groupRegistry struct {
+	l sync.RWMutex
+	m map[string][]*StateGroup
+}
+
+// add adds group to registry. Lock must be held.
+func (r *groupRegistry) add(group *StateGroup) {
+	for _, g := range r.m[group.Prefix] {
+		if g == group {
+			return
+		}
+	}
+	r.m[group.Prefix] = append(r.m[group.Prefix], group)
+}
+
+// groupKeys returns associated data keys of groups
+// with prefix of given state.
+func groupKeys(s State) (prefix string, keys []string) {
+	prefix, _ = s.Parse()
+	if prefix == "" {
+		return "", nil
+	}
+
+	groups.l.RLock()
+	defer groups.l.RUnlock()
+	for _, group := range groups.m[prefix] {
+		for _, key := range group.keys {
+			if !containsKey(keys, key) {
+				keys = append(keys, key)
+			}
+		}
+	}
+	return prefix, keys
+}
+
+// hasGroupKeys indicates what some groups have associated keys.
+func hasGroupKeys() bool {
+	groups.l.RLock()
+	defer groups.l.RUnlock()
+	return len(groups.m) > 0
+}
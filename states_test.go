@@ -0,0 +1,58 @@
+package fsm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestState_Parse(t *testing.T) {
+	tests := []struct {
+		name      string
+		s         State
+		wantGroup string
+		wantName  string
+	}{
+		{
+			name:      "state in group",
+			s:         "reg@name",
+			wantGroup: "reg",
+			wantName:  "name",
+		},
+		{
+			name:     "state without group",
+			s:        "my_state",
+			wantName: "my_state",
+		},
+		{
+			name: "default state",
+			s:    DefaultState,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			group, name := tt.s.Parse()
+			assert.Equalf(t, tt.wantGroup, group, "Parse() group of %v", tt.s)
+			assert.Equalf(t, tt.wantName, name, "Parse() name of %v", tt.s)
+		})
+	}
+}
+
+func TestStateGroup_Navigation(t *testing.T) {
+	sg := NewStateGroup("reg", "name", "age", "hobby")
+
+	assert.Equal(t, State("reg@name"), sg.First(), "First()")
+	assert.Equal(t, State("reg@hobby"), sg.Last(), "Last()")
+	assert.Equal(t, 1, sg.Index("reg@age"), "Index(reg@age)")
+	assert.Equal(t, -1, sg.Index("age"), "Index(age)")
+	assert.True(t, sg.Contains("reg@hobby"), "Contains(reg@hobby)")
+	assert.False(t, sg.Contains(DefaultState), "Contains(DefaultState)")
+
+	assert.Equal(t, State("reg@age"), sg.Next("reg@name"), "Next(reg@name)")
+	assert.Equal(t, DefaultState, sg.Next("reg@hobby"), "Next(reg@hobby)")
+	assert.Equal(t, DefaultState, sg.Previous("reg@name"), "Previous(reg@name)")
+
+	empty := NewStateGroup("empty")
+	assert.Equal(t, DefaultState, empty.First(), "First() of empty group")
+	assert.Equal(t, DefaultState, empty.Last(), "Last() of empty group")
+}
@@ -1,6 +1,9 @@
 package fsm
 
-import "errors"
+import (
+	"errors"
+	"io"
+)
 
 // ErrNotFound returns if data not found.
 var ErrNotFound = errors.New("fsm/storage: not found")
@@ -38,3 +41,176 @@ type Storage interface {
 	// with storage connection.
 	Close() error
 }
+
+// ErrNotSupported returns if storage doesn't support operation.
+var ErrNotSupported = errors.New("fsm/storage: operation not supported")
+
+// StorageKey is address of record in storage.
+type StorageKey struct {
+	ChatID int64
+	UserID int64
+
+	// ThreadID is message thread (forum topic) in chat.
+	// Zero value is chat without topics or general topic.
+	ThreadID int
+
+	// Machine is name of independent state machine.
+	// Empty string is default machine.
+	Machine string
+}
+
+// isBase indicates what key can be addressed by methods of Storage.
+// Thread and reserved machines aren't checked, see AsKeyStorage.
+func (k StorageKey) isBase() bool {
+	return k.Machine == "" || isReservedMachine(k.Machine)
+}
+
+// KeyStorage is optional extension of Storage what addresses
+// records by StorageKey. It allows keeping several independent
+// records (state and data) for one pair of chat and user.
+//
+// Methods of base Storage must work the same way as KeyStorage
+// methods with key what contains only chat and user.
+type KeyStorage interface {
+	Storage
+
+	// GetStateByKey returns State for key.
+	GetStateByKey(key StorageKey) (State, error)
+
+	// SetStateByKey sets state for key.
+	SetStateByKey(key StorageKey, state State) error
+
+	// ResetStateByKey deletes state for key. If `withData` is true
+	// deletes data of key from storage.
+	ResetStateByKey(key StorageKey, withData bool) error
+
+	// UpdateDataByKey sets, updates or deletes data for key.
+	// When data argument is nil it must deletes this item.
+	UpdateDataByKey(key StorageKey, dataKey string, data any) error
+
+	// GetDataByKey gets data for key and saves it into `to` argument.
+	// Destination argument must be a valid pointer.
+	GetDataByKey(key StorageKey, dataKey string, to any) error
+}
+
+// StateSwapper is optional capability of storage what sets state
+// only if current state is expected one. See Context.NextIn.
+type StateSwapper interface {
+	// SwapStateByKey sets state `next` for key if current state
+	// is `old` atomically. It reports whether state was set.
+	SwapStateByKey(key StorageKey, old, next State) (bool, error)
+}
+
+// ChatMigrator is optional capability of storage what moves
+// records of chat to other chat. It's needed when group
+// migrates to supergroup and gets new chat id.
+//
+// Use MigrateChat function or middleware.MigrateChat for
+// migration of chat with scheduled timeouts and jobs.
+type ChatMigrator interface {
+	// MigrateChat moves all records (states and data) of chat
+	// `from` to chat `to` atomically. Records of `to` with the
+	// same user, thread and machine are replaced.
+	MigrateChat(from, to int64) error
+}
+
+// MigrateChat moves records of chat `from` to chat `to` by
+// ChatMigrator of storage. Unlike direct call of ChatMigrator
+// it keeps scheduled timeouts and jobs of chat (see RunScheduler).
+// Storage must be storage of manager.
+func MigrateChat(storage Storage, from, to int64) error {
+	migrator, ok := storage.(ChatMigrator)
+	if !ok {
+		return ErrNotSupported
+	}
+	if err := migrator.MigrateChat(from, to); err != nil {
+		return err
+	}
+	return addMigration(storage, from, to)
+}
+
+// UserRecordsStorage is optional capability of storage what
+// works with all records of user across all chats. It helps to
+// honor requests of users to export or delete their data.
+type UserRecordsStorage interface {
+	// DeleteUser deletes all records (states and data)
+	// of user in all chats, including records of private
+	// chat with user (see Context.ChatData).
+	DeleteUser(userID int64) error
+
+	// ExportUser writes all records of user in all chats
+	// to w as JSON document (see file.WriteJSON). Internal
+	// records are skipped (see IsInternalMachine).
+	ExportUser(userID int64, w io.Writer) error
+}
+
+// MemberRecordsStorage is optional capability of storage what
+// works with all records of user in one chat: records of forum
+// topics and named machines. See middleware.Cleanup.
+type MemberRecordsStorage interface {
+	// ResetMember resets states of all records of user
+	// in chat. If `withData` is true deletes their data.
+	// Scheduled timeouts and jobs of user are deleted.
+	ResetMember(chatID, userID int64, withData bool) error
+}
+
+// AsKeyStorage returns storage as KeyStorage.
+//
+// If storage doesn't implement KeyStorage it will be wrapped.
+// Wrapped storage works only with keys what contains chat
+// and user, for other keys it returns ErrNotSupported.
+// Thread of key is ignored, so all topics of chat share
+// one record like before support of topics. Data scopes
+// (see Context.ChatData) are stored in records of
+// chat or user, so they share data keys with states
+// addressed by chat or user only.
+func AsKeyStorage(storage Storage) KeyStorage {
+	if ks, ok := storage.(KeyStorage); ok {
+		return ks
+	}
+	return baseKeyStorage{storage}
+}
+
+// baseKeyStorage adapts Storage to KeyStorage.
+type baseKeyStorage struct {
+	Storage
+}
+
+func (s baseKeyStorage) Unwrap() Storage {
+	return s.Storage
+}
+
+func (s baseKeyStorage) GetStateByKey(key StorageKey) (State, error) {
+	if !key.isBase() {
+		return DefaultState, ErrNotSupported
+	}
+	return s.GetState(key.ChatID, key.UserID)
+}
+
+func (s baseKeyStorage) SetStateByKey(key StorageKey, state State) error {
+	if !key.isBase() {
+		return ErrNotSupported
+	}
+	return s.SetState(key.ChatID, key.UserID, state)
+}
+
+func (s baseKeyStorage) ResetStateByKey(key StorageKey, withData bool) error {
+	if !key.isBase() {
+		return ErrNotSupported
+	}
+	return s.ResetState(key.ChatID, key.UserID, withData)
+}
+
+func (s baseKeyStorage) UpdateDataByKey(key StorageKey, dataKey string, data any) error {
+	if !key.isBase() {
+		return ErrNotSupported
+	}
+	return s.UpdateData(key.ChatID, key.UserID, dataKey, data)
+}
+
+func (s baseKeyStorage) GetDataByKey(key StorageKey, dataKey string, to any) error {
+	if !key.isBase() {
+		return ErrNotSupported
+	}
+	return s.GetData(key.ChatID, key.UserID, dataKey, to)
+}
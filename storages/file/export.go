@@ -1,6 +1,11 @@
 package file
 
-import "github.com/vitaliy-ukiru/fsm-telebot"
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/vitaliy-ukiru/fsm-telebot"
+)
 
 type (
 	// ChatsStorage in intermediate representation for data in Storage.
@@ -12,6 +17,14 @@ type (
 	Record       struct {
 		State string            `json:"state"`
 		Data  map[string][]byte `json:"data"`
+
+		// Machines contains records of named state machines.
+		// Records inside don't contain machines.
+		Machines map[string]Record `json:"machines,omitempty"`
+
+		// Threads contains records of message threads (forum topics).
+		// Records inside can contain machines, but not threads.
+		Threads map[int]Record `json:"threads,omitempty"`
 	}
 
 	ChatID = int64
@@ -52,42 +65,172 @@ func (s *Storage) dump() (ChatsStorage, error) {
 	s.rw.RLock()
 	defer s.rw.RUnlock()
 
+	return s.dumpFunc(func(chatKey) bool { return true }, nil)
+}
+
+// dumpFunc dumps records what keys match filter. If keepData
+// isn't nil only data keys what it keeps are dumped.
+// Lock must be held.
+func (s *Storage) dumpFunc(filter func(key chatKey) bool, keepData func(key string) bool) (ChatsStorage, error) {
 	chats := make(ChatsStorage)
 	for key, r := range s.data {
-		chat, ok := chats[key.c]
-		if !ok {
-			chat = make(UsersStorage)
+		if !filter(key) {
+			continue
 		}
 
 		exportData, err := r.exportData(s.p)
 		if err != nil {
 			return nil, err
 		}
+		if keepData != nil {
+			for dataKey := range exportData {
+				if !keepData(dataKey) {
+					delete(exportData, dataKey)
+				}
+			}
+		}
 
-		chat[key.u] = Record{
+		chats.Put(key.storageKey(), Record{
 			State: string(r.state),
 			Data:  exportData,
-		}
-		chats[key.c] = chat
+		})
 	}
 	return chats, nil
 }
 
+// Put places record by key. Records of machines
+// and threads are nested into base record.
+func (cs ChatsStorage) Put(key fsm.StorageKey, r Record) {
+	users, ok := cs[key.ChatID]
+	if !ok {
+		users = make(UsersStorage)
+		cs[key.ChatID] = users
+	}
+
+	base := users[key.UserID]
+	base.put(key.ThreadID, key.Machine, r)
+	users[key.UserID] = base
+}
+
+// JSONRecord is readable JSON representation of Record.
+// Data values are stored as raw JSON instead of base64
+// strings. See WriteJSON and provider.PrettyJson.
+type JSONRecord struct {
+	State    string                     `json:"state"`
+	Data     map[string]json.RawMessage `json:"data"`
+	Machines map[string]JSONRecord      `json:"machines,omitempty"`
+	Threads  map[int]JSONRecord         `json:"threads,omitempty"`
+}
+
+// WriteJSON writes storage to w as readable JSON document.
+// Records are converted by Record.ToJSON.
+func WriteJSON(w io.Writer, chats ChatsStorage) error {
+	doc := make(map[ChatID]map[UserID]JSONRecord, len(chats))
+	for chatId, users := range chats {
+		docUsers := make(map[UserID]JSONRecord, len(users))
+		for userId, r := range users {
+			docUsers[userId] = r.ToJSON()
+		}
+		doc[chatId] = docUsers
+	}
+
+	e := json.NewEncoder(w)
+	e.SetIndent("", "  ")
+	return e.Encode(doc)
+}
+
+// ToJSON converts record to JSONRecord. Data values what
+// are valid JSON are kept as is, other values are
+// converted to base64 strings.
+func (r Record) ToJSON() JSONRecord {
+	jr := JSONRecord{
+		State: r.State,
+		Data:  make(map[string]json.RawMessage, len(r.Data)),
+	}
+
+	for key, raw := range r.Data {
+		if !json.Valid(raw) {
+			// marshaling of []byte never fails
+			raw, _ = json.Marshal(raw)
+		}
+		jr.Data[key] = raw
+	}
+
+	if len(r.Machines) > 0 {
+		jr.Machines = make(map[string]JSONRecord, len(r.Machines))
+		for name, mr := range r.Machines {
+			jr.Machines[name] = mr.ToJSON()
+		}
+	}
+
+	if len(r.Threads) > 0 {
+		jr.Threads = make(map[int]JSONRecord, len(r.Threads))
+		for thread, tr := range r.Threads {
+			jr.Threads[thread] = tr.ToJSON()
+		}
+	}
+	return jr
+}
+
 func (s *Storage) reset(dump ChatsStorage) {
 	s.rw.Lock()
 	defer s.rw.Unlock()
 
 	for chatId, usersStorage := range dump {
 		for userId, r := range usersStorage {
-			data := make(map[string]dataCache)
-			for key, d := range r.Data {
-				data[key] = dataCache{raw: d}
-			}
+			s.importRecords(newKey(chatId, userId), r)
+		}
+	}
+}
 
-			s.data[newKey(chatId, userId)] = record{
-				state: fsm.State(r.State),
-				data:  data,
-			}
+// put places record of thread and machine into r.
+func (r *Record) put(thread int, machine string, exported Record) {
+	switch {
+	case thread != 0:
+		if r.Threads == nil {
+			r.Threads = make(map[int]Record)
 		}
+		tr := r.Threads[thread]
+		tr.put(0, machine, exported)
+		r.Threads[thread] = tr
+	case machine == "":
+		exported.Machines = r.Machines
+		exported.Threads = r.Threads
+		*r = exported
+	default:
+		if r.Machines == nil {
+			r.Machines = make(map[string]Record)
+		}
+		r.Machines[machine] = exported
+	}
+}
+
+// importRecords imports record with its machines and threads.
+// Lock must be held.
+func (s *Storage) importRecords(key chatKey, r Record) {
+	s.data[key] = r.importRecord()
+
+	for machine, mr := range r.Machines {
+		mk := key
+		mk.m = machine
+		s.data[mk] = mr.importRecord()
+	}
+
+	for thread, tr := range r.Threads {
+		tk := key
+		tk.t = thread
+		s.importRecords(tk, tr)
+	}
+}
+
+func (r Record) importRecord() record {
+	data := make(map[string]dataCache)
+	for key, d := range r.Data {
+		data[key] = dataCache{raw: d}
+	}
+
+	return record{
+		state: fsm.State(r.State),
+		data:  data,
 	}
 }
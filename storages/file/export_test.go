@@ -0,0 +1,93 @@
+package file
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vitaliy-ukiru/fsm-telebot"
+)
+
+func TestStorage_dumpReset(t *testing.T) {
+	const (
+		c int64 = 66
+		u int64 = 33
+	)
+
+	s := NewStorage(nil, nil)
+	s.data = map[chatKey]record{
+		newKey(c, u): {
+			state: "reg@name",
+			data:  map[string]dataCache{"name": {raw: []byte(`"foo"`)}},
+		},
+		{c: c, u: u, m: "cart"}: {
+			state: "cart@items",
+			data:  map[string]dataCache{"items": {raw: []byte(`2`)}},
+		},
+		{c: c, u: u, t: 7}: {
+			state: "topic@name",
+		},
+		{c: c, u: u, t: 7, m: "cart"}: {
+			state: "cart@pay",
+		},
+	}
+
+	dump, err := s.dump()
+	require.NoError(t, err)
+	assert.Equal(t, ChatsStorage{
+		c: {
+			u: {
+				State: "reg@name",
+				Data:  map[string][]byte{"name": []byte(`"foo"`)},
+				Machines: map[string]Record{
+					"cart": {
+						State: "cart@items",
+						Data:  map[string][]byte{"items": []byte(`2`)},
+					},
+				},
+				Threads: map[int]Record{
+					7: {
+						State:    "topic@name",
+						Machines: map[string]Record{"cart": {State: "cart@pay"}},
+					},
+				},
+			},
+		},
+	}, dump)
+
+	restored := NewStorage(nil, nil)
+	restored.reset(dump)
+
+	state, err := restored.GetStateByKey(fsm.StorageKey{ChatID: c, UserID: u, Machine: "cart"})
+	require.NoError(t, err)
+	assert.Equal(t, fsm.State("cart@items"), state, "restored machine state")
+
+	state, err = restored.GetStateByKey(fsm.StorageKey{ChatID: c, UserID: u, ThreadID: 7, Machine: "cart"})
+	require.NoError(t, err)
+	assert.Equal(t, fsm.State("cart@pay"), state, "restored machine state of thread")
+
+	state, err = restored.GetState(c, u)
+	require.NoError(t, err)
+	assert.Equal(t, fsm.State("reg@name"), state, "restored default state")
+}
+
+func TestWriteJSON(t *testing.T) {
+	buf := new(bytes.Buffer)
+	require.NoError(t, WriteJSON(buf, ChatsStorage{
+		1: {2: {
+			State: "s",
+			Data: map[string][]byte{
+				"json": []byte(`{"a":1}`),
+				"gob":  {0xff, 0x01},
+			},
+			Threads: map[int]Record{3: {State: "t"}},
+		}},
+	}))
+
+	assert.JSONEq(t, `{"1": {"2": {
+		"state": "s",
+		"data": {"json": {"a": 1}, "gob": "/wE="},
+		"threads": {"3": {"state": "t", "data": {}}}
+	}}}`, buf.String())
+}
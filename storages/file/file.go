@@ -26,9 +26,11 @@ type Provider interface {
 	Decode(data []byte, v any) error
 }
 
-// chatKey represents  pair {c: chat id, u: user id}
+// chatKey represents  {c: chat id, u: user id, t: thread, m: machine}
 type chatKey struct {
 	c, u int64
+	t    int
+	m    string
 }
 
 func newKey(chat, user int64) chatKey {
@@ -38,6 +40,29 @@ func newKey(chat, user int64) chatKey {
 	}
 }
 
+func keyOf(key fsm.StorageKey) chatKey {
+	return chatKey{
+		c: key.ChatID,
+		u: key.UserID,
+		t: key.ThreadID,
+		m: key.Machine,
+	}
+}
+
+func (k chatKey) storageKey() fsm.StorageKey {
+	return fsm.StorageKey{ChatID: k.c, UserID: k.u, ThreadID: k.t, Machine: k.m}
+}
+
+// ofUser indicates what record belongs to user: records of user
+// and records of private chat with user (like chat data).
+func (k chatKey) ofUser(userID int64) bool {
+	return k.u == userID || (k.c == userID && k.u == 0)
+}
+
+func baseKey(chat, user int64) fsm.StorageKey {
+	return fsm.StorageKey{ChatID: chat, UserID: user}
+}
+
 type record struct {
 	state fsm.State
 	data  map[string]dataCache
@@ -89,21 +114,52 @@ func (s *Storage) Init(r io.Reader) error {
 }
 
 func (s *Storage) GetState(chatId, userId int64) (fsm.State, error) {
+	return s.GetStateByKey(baseKey(chatId, userId))
+}
+
+func (s *Storage) SetState(chatId, userId int64, state fsm.State) error {
+	return s.SetStateByKey(baseKey(chatId, userId), state)
+}
+
+func (s *Storage) ResetState(chatId, userId int64, withData bool) error {
+	return s.ResetStateByKey(baseKey(chatId, userId), withData)
+}
+
+func (s *Storage) UpdateData(chatId, userId int64, key string, data any) error {
+	return s.UpdateDataByKey(baseKey(chatId, userId), key, data)
+}
+
+func (s *Storage) GetData(chatId, userId int64, key string, to any) error {
+	return s.GetDataByKey(baseKey(chatId, userId), key, to)
+}
+
+func (s *Storage) GetStateByKey(key fsm.StorageKey) (fsm.State, error) {
 	s.rw.RLock()
 	defer s.rw.RUnlock()
-	key := newKey(chatId, userId)
-	return s.data[key].state, nil
+	return s.data[keyOf(key)].state, nil
 }
 
-func (s *Storage) SetState(chatId, userId int64, state fsm.State) error {
-	s.do(chatId, userId, func(r *record) {
+func (s *Storage) SetStateByKey(key fsm.StorageKey, state fsm.State) error {
+	s.do(keyOf(key), func(r *record) {
 		r.state = state
 	})
 	return nil
 }
 
-func (s *Storage) ResetState(chatId, userId int64, withData bool) error {
-	s.do(chatId, userId, func(r *record) {
+// SwapStateByKey implements fsm.StateSwapper.
+func (s *Storage) SwapStateByKey(key fsm.StorageKey, old, next fsm.State) (bool, error) {
+	var swapped bool
+	s.do(keyOf(key), func(r *record) {
+		if r.state == old {
+			r.state = next
+			swapped = true
+		}
+	})
+	return swapped, nil
+}
+
+func (s *Storage) ResetStateByKey(key fsm.StorageKey, withData bool) error {
+	s.do(keyOf(key), func(r *record) {
 		r.state = ""
 		if withData {
 			for key := range r.data {
@@ -114,24 +170,24 @@ func (s *Storage) ResetState(chatId, userId int64, withData bool) error {
 	return nil
 }
 
-func (s *Storage) UpdateData(chatId, userId int64, key string, data any) error {
-	s.do(chatId, userId, func(r *record) {
+func (s *Storage) UpdateDataByKey(key fsm.StorageKey, dataKey string, data any) error {
+	s.do(keyOf(key), func(r *record) {
 		if r.data == nil {
 			r.data = make(map[string]dataCache)
 		}
 		if data == nil {
-			delete(r.data, key)
+			delete(r.data, dataKey)
 		} else {
-			r.data[key] = dataCache{loaded: data}
+			r.data[dataKey] = dataCache{loaded: data}
 		}
 	})
 	return nil
 }
 
-func (s *Storage) GetData(chatId, userId int64, key string, to any) error {
+func (s *Storage) GetDataByKey(key fsm.StorageKey, dataKey string, to any) error {
 	s.rw.RLock()
 	defer s.rw.RUnlock()
-	d, ok := s.data[newKey(chatId, userId)].data[key]
+	d, ok := s.data[keyOf(key)].data[dataKey]
 	if !ok {
 		return fsm.ErrNotFound
 	}
@@ -139,6 +195,74 @@ func (s *Storage) GetData(chatId, userId int64, key string, to any) error {
 	return d.get(to, s.p)
 }
 
+// MigrateChat implements fsm.ChatMigrator.
+func (s *Storage) MigrateChat(from, to int64) error {
+	if from == to {
+		return nil
+	}
+
+	s.rw.Lock()
+	defer s.rw.Unlock()
+
+	for key, r := range s.data {
+		if key.c != from {
+			continue
+		}
+		delete(s.data, key)
+		key.c = to
+		s.data[key] = r
+	}
+	return nil
+}
+
+// ResetMember implements fsm.MemberRecordsStorage.
+func (s *Storage) ResetMember(chatID, userID int64, withData bool) error {
+	s.rw.Lock()
+	defer s.rw.Unlock()
+
+	for key, r := range s.data {
+		if key.c != chatID || key.u != userID {
+			continue
+		}
+		// scheduled timeouts and jobs are dropped too
+		if withData || fsm.IsInternalMachine(key.m) {
+			delete(s.data, key)
+			continue
+		}
+		r.state = ""
+		s.data[key] = r
+	}
+	return nil
+}
+
+// DeleteUser implements fsm.UserRecordsStorage.
+func (s *Storage) DeleteUser(userID int64) error {
+	s.rw.Lock()
+	defer s.rw.Unlock()
+
+	for key := range s.data {
+		if key.ofUser(userID) {
+			delete(s.data, key)
+		}
+	}
+	return nil
+}
+
+// ExportUser implements fsm.UserRecordsStorage.
+func (s *Storage) ExportUser(userID int64, w io.Writer) error {
+	s.rw.RLock()
+	chats, err := s.dumpFunc(func(key chatKey) bool {
+		return key.ofUser(userID) && !fsm.IsInternalMachine(key.m)
+	}, func(key string) bool {
+		return !fsm.IsInternalDataKey(key)
+	})
+	s.rw.RUnlock()
+	if err != nil {
+		return err
+	}
+	return WriteJSON(w, chats)
+}
+
 // Close saves storage data to writer from writer function.
 //
 // Also, the method closes writer, minimum once time.
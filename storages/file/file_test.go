@@ -0,0 +1,103 @@
+package file
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vitaliy-ukiru/fsm-telebot"
+)
+
+func TestStorage_MigrateChat(t *testing.T) {
+	const (
+		from int64 = -10
+		to   int64 = -100
+	)
+
+	s := NewStorage(nil, nil)
+	require.NoError(t, s.SetState(from, 1, "first"))
+	require.NoError(t, s.UpdateData(from, 1, "foo", "bar"))
+	require.NoError(t, s.SetStateByKey(fsm.StorageKey{ChatID: from, UserID: 2, ThreadID: 3, Machine: "m"}, "machine"))
+	require.NoError(t, s.SetState(-20, 1, "other"))
+
+	require.NoError(t, s.MigrateChat(from, to))
+
+	tests := []struct {
+		name string
+		key  fsm.StorageKey
+		want fsm.State
+	}{
+		{name: "moved", key: fsm.StorageKey{ChatID: to, UserID: 1}, want: "first"},
+		{name: "moved machine", key: fsm.StorageKey{ChatID: to, UserID: 2, ThreadID: 3, Machine: "m"}, want: "machine"},
+		{name: "old chat", key: fsm.StorageKey{ChatID: from, UserID: 1}, want: fsm.DefaultState},
+		{name: "other chat", key: fsm.StorageKey{ChatID: -20, UserID: 1}, want: "other"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.GetStateByKey(tt.key)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	var data string
+	require.NoError(t, s.GetData(to, 1, "foo", &data))
+	assert.Equal(t, "bar", data, "moved data")
+}
+
+func TestStorage_ResetMember(t *testing.T) {
+	s := NewStorage(nil, nil)
+	keys := []fsm.StorageKey{
+		{ChatID: -10, UserID: 1},
+		{ChatID: -10, UserID: 1, ThreadID: 3, Machine: "m"},
+	}
+	other := fsm.StorageKey{ChatID: -10, UserID: 2}
+	schedule := fsm.StorageKey{ChatID: -10, UserID: 1, Machine: "__fsm_schedule"}
+	for _, key := range append(keys, other, schedule) {
+		require.NoError(t, s.SetStateByKey(key, "state"))
+		require.NoError(t, s.UpdateDataByKey(key, "foo", "bar"))
+	}
+
+	require.NoError(t, s.ResetMember(-10, 1, false))
+	var data string
+	for _, key := range keys {
+		state, err := s.GetStateByKey(key)
+		require.NoError(t, err)
+		assert.Equal(t, fsm.DefaultState, state, "reset state")
+		assert.NoError(t, s.GetDataByKey(key, "foo", &data), "kept data")
+	}
+	assert.ErrorIs(t, s.GetDataByKey(schedule, "foo", &data), fsm.ErrNotFound, "purged schedule")
+
+	require.NoError(t, s.ResetMember(-10, 1, true))
+	for _, key := range keys {
+		assert.ErrorIs(t, s.GetDataByKey(key, "foo", &data), fsm.ErrNotFound, "purged data")
+	}
+
+	state, err := s.GetStateByKey(other)
+	require.NoError(t, err)
+	assert.Equal(t, fsm.State("state"), state, "other user")
+}
+
+// jsonProvider encodes values by encoding/json.
+type jsonProvider struct{}
+
+func (jsonProvider) ProviderName() string                 { return "json" }
+func (jsonProvider) Save(io.Writer, ChatsStorage) error   { return nil }
+func (jsonProvider) Read(io.Reader) (ChatsStorage, error) { return nil, nil }
+func (jsonProvider) Encode(v any) ([]byte, error)         { return json.Marshal(v) }
+func (jsonProvider) Decode(data []byte, v any) error      { return json.Unmarshal(data, v) }
+
+func TestStorage_ExportUser(t *testing.T) {
+	s := NewStorage(jsonProvider{}, nil)
+	require.NoError(t, s.SetState(-10, 1, "form@age"))
+	require.NoError(t, s.UpdateData(-10, 1, "age", 23))
+	require.NoError(t, s.UpdateData(-10, 1, "__fsm_session", "id"))
+	require.NoError(t, s.UpdateDataByKey(fsm.StorageKey{ChatID: -10, UserID: 1, Machine: "__fsm_schedule"}, "foo", "bar"))
+
+	buf := new(bytes.Buffer)
+	require.NoError(t, s.ExportUser(1, buf))
+	assert.JSONEq(t, `{"-10": {"1": {"state": "form@age", "data": {"age": 23}}}}`, buf.String())
+}
@@ -104,10 +104,7 @@ func (j PrettyJson) Read(r io.Reader) (file.ChatsStorage, error) {
 }
 
 type jsonStorage map[int64]map[int64]record
-type record struct {
-	State string                     `json:"state"`
-	Data  map[string]json.RawMessage `json:"data"`
-}
+type record = file.JSONRecord
 
 func (PrettyJson) tryDecodeB64(enc *b64.Encoding, src []byte) ([]byte, bool) {
 	if src[0] != '"' && src[len(src)-1] != '"' {
@@ -123,19 +120,12 @@ func (PrettyJson) tryDecodeB64(enc *b64.Encoding, src []byte) ([]byte, bool) {
 	return buf[:n], true
 }
 
-func (PrettyJson) convertTo(storage file.ChatsStorage) jsonStorage {
+func (j PrettyJson) convertTo(storage file.ChatsStorage) jsonStorage {
 	result := make(jsonStorage)
 	for chatId, usersStorage := range storage {
 		usersData := make(map[int64]record)
 		for userId, r := range usersStorage {
-			data := make(map[string]json.RawMessage)
-			for key, raw := range r.Data {
-				data[key] = raw
-			}
-			usersData[userId] = record{
-				State: r.State,
-				Data:  data,
-			}
+			usersData[userId] = r.ToJSON()
 		}
 		result[chatId] = usersData
 	}
@@ -147,22 +137,45 @@ func (j PrettyJson) convertFrom(storage jsonStorage) file.ChatsStorage {
 	for chatId, usersStorage := range storage {
 		usersData := make(file.UsersStorage)
 		for userId, r := range usersStorage {
-			data := make(map[string][]byte)
-			for key, raw := range r.Data {
-				if j.TryDecodeBase64String {
-					decoded, ok := j.tryDecodeB64(b64.StdEncoding, raw)
-					if ok {
-						raw = decoded
-					}
-				}
-				data[key] = raw
-			}
-			usersData[userId] = file.Record{
-				State: r.State,
-				Data:  data,
-			}
+			usersData[userId] = j.recordFrom(r)
 		}
 		result[chatId] = usersData
 	}
 	return result
 }
+
+func (j PrettyJson) recordFrom(r record) file.Record {
+	data := make(map[string][]byte)
+	for key, raw := range r.Data {
+		if j.TryDecodeBase64String {
+			decoded, ok := j.tryDecodeB64(b64.StdEncoding, raw)
+			if ok {
+				raw = decoded
+			}
+		}
+		data[key] = raw
+	}
+
+	var machines map[string]file.Record
+	if len(r.Machines) > 0 {
+		machines = make(map[string]file.Record)
+		for name, mr := range r.Machines {
+			machines[name] = j.recordFrom(mr)
+		}
+	}
+
+	var threads map[int]file.Record
+	if len(r.Threads) > 0 {
+		threads = make(map[int]file.Record)
+		for thread, tr := range r.Threads {
+			threads[thread] = j.recordFrom(tr)
+		}
+	}
+
+	return file.Record{
+		State:    r.State,
+		Data:     data,
+		Machines: machines,
+		Threads:  threads,
+	}
+}
@@ -2,11 +2,14 @@
 package memory
 
 import (
+	"encoding/json"
+	"io"
 	"reflect"
 	"sync"
 
 	"github.com/vitaliy-ukiru/fsm-telebot"
 	"github.com/vitaliy-ukiru/fsm-telebot/storages"
+	"github.com/vitaliy-ukiru/fsm-telebot/storages/file"
 )
 
 // Storage is storage based on RAM. Drops if you stop script.
@@ -29,20 +32,39 @@ type record struct {
 }
 
 type chatKey struct {
-	c int64 // c is Chat ID
-	u int64 // u is User ID
+	c int64  // c is Chat ID
+	u int64  // u is User ID
+	t int    // t is thread ID
+	m string // m is machine name
 }
 
 func newKey(chat, user int64) chatKey {
 	return chatKey{c: chat, u: user}
 }
 
+func keyOf(key fsm.StorageKey) chatKey {
+	return chatKey{c: key.ChatID, u: key.UserID, t: key.ThreadID, m: key.Machine}
+}
+
+func (k chatKey) storageKey() fsm.StorageKey {
+	return fsm.StorageKey{ChatID: k.c, UserID: k.u, ThreadID: k.t, Machine: k.m}
+}
+
+// ofUser indicates what record belongs to user: records of user
+// and records of private chat with user (like chat data).
+func (k chatKey) ofUser(userID int64) bool {
+	return k.u == userID || (k.c == userID && k.u == 0)
+}
+
+func baseKey(chat, user int64) fsm.StorageKey {
+	return fsm.StorageKey{ChatID: chat, UserID: user}
+}
+
 // do exec `call` and save modification to storage.
 // It helps not to copy the code.
-func (m *Storage) do(chat, user int64, call func(*record)) {
+func (m *Storage) do(key chatKey, call func(*record)) {
 	m.l.Lock()
 	defer m.l.Unlock()
-	key := newKey(chat, user)
 
 	r := m.storage[key]
 	call(&r)
@@ -50,21 +72,52 @@ func (m *Storage) do(chat, user int64, call func(*record)) {
 }
 
 func (m *Storage) GetState(chatId, userId int64) (fsm.State, error) {
+	return m.GetStateByKey(baseKey(chatId, userId))
+}
+
+func (m *Storage) SetState(chatId, userId int64, state fsm.State) error {
+	return m.SetStateByKey(baseKey(chatId, userId), state)
+}
+
+func (m *Storage) ResetState(chatId, userId int64, withData bool) error {
+	return m.ResetStateByKey(baseKey(chatId, userId), withData)
+}
+
+func (m *Storage) UpdateData(chatId, userId int64, key string, data any) error {
+	return m.UpdateDataByKey(baseKey(chatId, userId), key, data)
+}
+
+func (m *Storage) GetData(chatId, userId int64, key string, to any) error {
+	return m.GetDataByKey(baseKey(chatId, userId), key, to)
+}
+
+func (m *Storage) GetStateByKey(key fsm.StorageKey) (fsm.State, error) {
 	m.l.RLock()
 	defer m.l.RUnlock()
-	key := newKey(chatId, userId)
-	return m.storage[key].state, nil
+	return m.storage[keyOf(key)].state, nil
 }
 
-func (m *Storage) SetState(chatId, userId int64, state fsm.State) error {
-	m.do(chatId, userId, func(r *record) {
+func (m *Storage) SetStateByKey(key fsm.StorageKey, state fsm.State) error {
+	m.do(keyOf(key), func(r *record) {
 		r.state = state
 	})
 	return nil
 }
 
-func (m *Storage) ResetState(chatId, userId int64, withData bool) error {
-	m.do(chatId, userId, func(r *record) {
+// SwapStateByKey implements fsm.StateSwapper.
+func (m *Storage) SwapStateByKey(key fsm.StorageKey, old, next fsm.State) (bool, error) {
+	var swapped bool
+	m.do(keyOf(key), func(r *record) {
+		if r.state == old {
+			r.state = next
+			swapped = true
+		}
+	})
+	return swapped, nil
+}
+
+func (m *Storage) ResetStateByKey(key fsm.StorageKey, withData bool) error {
+	m.do(keyOf(key), func(r *record) {
 		r.state = ""
 		if withData {
 			for key := range r.data {
@@ -75,26 +128,26 @@ func (m *Storage) ResetState(chatId, userId int64, withData bool) error {
 	return nil
 }
 
-func (m *Storage) UpdateData(chatId, userId int64, key string, data any) error {
-	m.do(chatId, userId, func(r *record) {
+func (m *Storage) UpdateDataByKey(key fsm.StorageKey, dataKey string, data any) error {
+	m.do(keyOf(key), func(r *record) {
 		if r.data == nil {
 			r.data = make(map[string]any)
 		}
 		if data == nil {
-			delete(r.data, key)
+			delete(r.data, dataKey)
 		} else {
-			r.data[key] = data
+			r.data[dataKey] = data
 		}
 	})
 	return nil
 }
 
-func (m *Storage) GetData(chatId, userId int64, key string, to any) error {
+func (m *Storage) GetDataByKey(key fsm.StorageKey, dataKey string, to any) error {
 	m.l.RLock()
 	defer m.l.RUnlock()
 
-	r := m.storage[newKey(chatId, userId)]
-	v, ok := r.data[key]
+	r := m.storage[keyOf(key)]
+	v, ok := r.data[dataKey]
 	if !ok {
 		return fsm.ErrNotFound
 	}
@@ -126,6 +179,99 @@ func (m *Storage) GetData(chatId, userId int64, key string, to any) error {
 	return nil
 }
 
+// MigrateChat implements fsm.ChatMigrator.
+func (m *Storage) MigrateChat(from, to int64) error {
+	if from == to {
+		return nil
+	}
+
+	m.l.Lock()
+	defer m.l.Unlock()
+
+	for key, r := range m.storage {
+		if key.c != from {
+			continue
+		}
+		delete(m.storage, key)
+		key.c = to
+		m.storage[key] = r
+	}
+	return nil
+}
+
+// ResetMember implements fsm.MemberRecordsStorage.
+func (m *Storage) ResetMember(chatID, userID int64, withData bool) error {
+	m.l.Lock()
+	defer m.l.Unlock()
+
+	for key, r := range m.storage {
+		if key.c != chatID || key.u != userID {
+			continue
+		}
+		// scheduled timeouts and jobs are dropped too
+		if withData || fsm.IsInternalMachine(key.m) {
+			delete(m.storage, key)
+			continue
+		}
+		r.state = ""
+		m.storage[key] = r
+	}
+	return nil
+}
+
+// DeleteUser implements fsm.UserRecordsStorage.
+func (m *Storage) DeleteUser(userID int64) error {
+	m.l.Lock()
+	defer m.l.Unlock()
+
+	for key := range m.storage {
+		if key.ofUser(userID) {
+			delete(m.storage, key)
+		}
+	}
+	return nil
+}
+
+// ExportUser implements fsm.UserRecordsStorage.
+// Data values are encoded by encoding/json.
+func (m *Storage) ExportUser(userID int64, w io.Writer) error {
+	chats, err := m.exportUser(userID)
+	if err != nil {
+		return err
+	}
+	return file.WriteJSON(w, chats)
+}
+
+func (m *Storage) exportUser(userID int64) (file.ChatsStorage, error) {
+	m.l.RLock()
+	defer m.l.RUnlock()
+
+	chats := make(file.ChatsStorage)
+	for key, r := range m.storage {
+		if !key.ofUser(userID) || fsm.IsInternalMachine(key.m) {
+			continue
+		}
+
+		data := make(map[string][]byte, len(r.data))
+		for dataKey, v := range r.data {
+			if fsm.IsInternalDataKey(dataKey) {
+				continue
+			}
+			raw, err := json.Marshal(v)
+			if err != nil {
+				return nil, err
+			}
+			data[dataKey] = raw
+		}
+
+		chats.Put(key.storageKey(), file.Record{
+			State: string(r.state),
+			Data:  data,
+		})
+	}
+	return chats, nil
+}
+
 func (m *Storage) Close() error {
 	return nil
 }
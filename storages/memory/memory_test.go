@@ -1,6 +1,7 @@
 package memory
 
 import (
+	"bytes"
 	"fmt"
 	"reflect"
 	"testing"
@@ -94,3 +95,76 @@ func TestStorage_GetData(t *testing.T) {
 		})
 	}
 }
+
+func TestStorage_MigrateChat(t *testing.T) {
+	const (
+		from int64 = -10
+		to   int64 = -100
+	)
+
+	s := NewStorage()
+	assert.NoError(t, s.SetState(from, 1, "first"))
+	assert.NoError(t, s.UpdateData(from, 1, "foo", "bar"))
+	assert.NoError(t, s.SetStateByKey(fsm.StorageKey{ChatID: from, UserID: 2, ThreadID: 3, Machine: "m"}, "machine"))
+	assert.NoError(t, s.SetState(-20, 1, "other"))
+
+	assert.NoError(t, s.MigrateChat(from, to))
+
+	tests := []struct {
+		name string
+		key  fsm.StorageKey
+		want fsm.State
+	}{
+		{name: "moved", key: fsm.StorageKey{ChatID: to, UserID: 1}, want: "first"},
+		{name: "moved machine", key: fsm.StorageKey{ChatID: to, UserID: 2, ThreadID: 3, Machine: "m"}, want: "machine"},
+		{name: "old chat", key: fsm.StorageKey{ChatID: from, UserID: 1}, want: fsm.DefaultState},
+		{name: "other chat", key: fsm.StorageKey{ChatID: -20, UserID: 1}, want: "other"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.GetStateByKey(tt.key)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	var data string
+	assert.NoError(t, s.GetData(to, 1, "foo", &data))
+	assert.Equal(t, "bar", data, "moved data")
+}
+
+func TestStorage_ExportDeleteUser(t *testing.T) {
+	s := NewStorage()
+	assert.NoError(t, s.SetState(-10, 1, "form@age"))
+	assert.NoError(t, s.UpdateData(-10, 1, "age", 23))
+	assert.NoError(t, s.UpdateData(-10, 1, "__fsm_session", "id"))
+	assert.NoError(t, s.SetStateByKey(fsm.StorageKey{ChatID: -10, UserID: 1, Machine: "cart"}, "cart@items"))
+	assert.NoError(t, s.UpdateData(0, 1, "lang", "en"))
+	assert.NoError(t, s.UpdateDataByKey(fsm.StorageKey{ChatID: 1, Machine: "chat"}, "theme", "dark"))
+	assert.NoError(t, s.SetState(-10, 2, "other"))
+
+	buf := new(bytes.Buffer)
+	assert.NoError(t, s.ExportUser(1, buf))
+	assert.JSONEq(t, `{
+		"-10": {"1": {
+			"state": "form@age",
+			"data": {"age": 23},
+			"machines": {"cart": {"state": "cart@items", "data": {}}}
+		}},
+		"0": {"1": {"state": "", "data": {"lang": "en"}}},
+		"1": {"0": {"state": "", "data": {}, "machines": {"chat": {"state": "", "data": {"theme": "dark"}}}}}
+	}`, buf.String())
+
+	assert.NoError(t, s.DeleteUser(1))
+
+	var lang string
+	assert.ErrorIs(t, s.GetData(0, 1, "lang", &lang), fsm.ErrNotFound, "user data after delete")
+	assert.ErrorIs(t, s.GetDataByKey(fsm.StorageKey{ChatID: 1, Machine: "chat"}, "theme", &lang),
+		fsm.ErrNotFound, "private chat data after delete")
+	state, err := s.GetState(-10, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, fsm.DefaultState, state, "user state after delete")
+	state, err = s.GetState(-10, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, fsm.State("other"), state, "other user state after delete")
+}
@@ -0,0 +1,56 @@
+package strategy
+
+import (
+	"github.com/vitaliy-ukiru/fsm-telebot"
+	tele "gopkg.in/telebot.v3"
+)
+
+// Addresser maps key of context to key of record in base storage.
+// Strategy and Func implement it.
+type Addresser interface {
+	Address(key fsm.StorageKey) fsm.StorageKey
+}
+
+// Func is custom addressing function.
+type Func func(key fsm.StorageKey) fsm.StorageKey
+
+// Address implements Addresser.
+func (f Func) Address(key fsm.StorageKey) fsm.StorageKey {
+	return f(key)
+}
+
+// ContextFunc is addressing function what depends on update.
+// Storage doesn't know about updates, so it applies in context
+// (see fsm.Addressing.Key).
+//
+//	manager := fsm.NewManager(bot, nil, storage,
+//		strategy.ByChatType(strategy.User, strategy.Chat).ContextMaker(),
+//	)
+type ContextFunc func(c tele.Context, key fsm.StorageKey) fsm.StorageKey
+
+// Addressing returns fsm.DefaultAddressing with this function.
+func (f ContextFunc) Addressing() fsm.Addressing {
+	a := fsm.DefaultAddressing
+	a.Key = f
+	return a
+}
+
+// ContextMaker returns context maker with this function.
+func (f ContextFunc) ContextMaker() fsm.ContextMakerFunc {
+	return f.Addressing().ContextMaker()
+}
+
+// ByChatType returns addressing what uses `private` for private
+// chats and updates without chat, and `group` for other chats.
+//
+// For example, state per user in private chats and per chat in groups:
+//
+//	strategy.ByChatType(strategy.User, strategy.Chat)
+func ByChatType(private, group Addresser) ContextFunc {
+	return func(c tele.Context, key fsm.StorageKey) fsm.StorageKey {
+		if chat := c.Chat(); chat == nil || chat.Type == tele.ChatPrivate {
+			return private.Address(key)
+		}
+		return group.Address(key)
+	}
+}
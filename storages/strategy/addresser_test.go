@@ -0,0 +1,84 @@
+package strategy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vitaliy-ukiru/fsm-telebot"
+	"github.com/vitaliy-ukiru/fsm-telebot/storages/memory"
+	tele "gopkg.in/telebot.v3"
+)
+
+func TestStorage_Func(t *testing.T) {
+	const admins int64 = -1
+	base := memory.NewStorage()
+	s := NewStorage(base, Func(func(key fsm.StorageKey) fsm.StorageKey {
+		if key.UserID == 1 || key.UserID == 2 {
+			key.UserID = admins
+		}
+		return key
+	}))
+
+	require.NoError(t, s.SetState(10, 1, "ban"))
+
+	state, err := s.GetState(10, 2)
+	require.NoError(t, err)
+	assert.Equal(t, fsm.State("ban"), state, "shared identity")
+
+	state, err = base.GetState(10, admins)
+	require.NoError(t, err)
+	assert.Equal(t, fsm.State("ban"), state, "base storage key")
+
+	assert.Equal(t, Empty, s.Strategy(), "strategy of custom addresser")
+}
+
+func TestByChatType(t *testing.T) {
+	f := ByChatType(User, Chat)
+	user := &tele.User{ID: 20}
+
+	tests := []struct {
+		name   string
+		update tele.Update
+		want   fsm.StorageKey
+	}{
+		{
+			name:   "private chat",
+			update: tele.Update{Message: &tele.Message{Chat: &tele.Chat{ID: 20, Type: tele.ChatPrivate}, Sender: user}},
+			want:   fsm.StorageKey{UserID: 20},
+		},
+		{
+			name:   "group",
+			update: tele.Update{Message: &tele.Message{Chat: &tele.Chat{ID: -10, Type: tele.ChatGroup}, Sender: user}},
+			want:   fsm.StorageKey{ChatID: -10},
+		},
+		{
+			name:   "without chat",
+			update: tele.Update{Query: &tele.Query{Sender: user}},
+			want:   fsm.StorageKey{UserID: 20},
+		},
+	}
+
+	var b tele.Bot
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, ok := f.Addressing().Resolve(b.NewContext(tt.update))
+			require.True(t, ok)
+			assert.Equal(t, tt.want, key)
+		})
+	}
+}
+
+func TestStorage_MigrateChat(t *testing.T) {
+	base := memory.NewStorage()
+	s := NewStorage(base, Chat)
+
+	require.NoError(t, s.SetState(-10, 1, "poll"))
+	require.NoError(t, s.MigrateChat(-10, -100))
+
+	state, err := s.GetState(-100, 2)
+	require.NoError(t, err)
+	assert.Equal(t, fsm.State("poll"), state, "chat state after migration")
+
+	assert.NoError(t, NewStorage(base, User).MigrateChat(-10, -100), "strategy without chat")
+}
@@ -1,11 +1,15 @@
 package strategy
 
-import "github.com/vitaliy-ukiru/fsm-telebot"
+import (
+	"io"
+	"strings"
+
+	"github.com/vitaliy-ukiru/fsm-telebot"
+)
 
 // Strategy for addressing. It works as bit set.
 //
-// So far, you can only combine [User] and [Chat],
-// but with the support of forums in telebot, we plan to add them as well.
+// You can combine [User], [Chat] and [Topic].
 //
 // Have "magic" value - [Empty]. It needs for safe support zero value.
 // This value must be just pass, without any logic.
@@ -23,9 +27,17 @@ const (
 	// Chat addressing. It will make one state for all users in one chat.
 	Chat
 
-	// Default is contains user and chat addressing.
-	// It will make state for every user in every chat.
-	Default = User | Chat
+	// Topic addressing. It will make separated states for every
+	// forum topic (message thread) of chat. It works only with
+	// Chat, because threads are part of chat.
+	Topic
+
+	// Default is contains user, chat and topic addressing.
+	// It will make state for every user in every topic of every chat.
+	Default = User | Chat | Topic
+
+	// all is all valid bits of strategy.
+	all = User | Chat | Topic
 )
 
 func (s Strategy) String() string {
@@ -38,57 +50,201 @@ func (s Strategy) String() string {
 		return "strategy.OnlyUser"
 	case Chat:
 		return "strategy.OnlyChat"
+	case Topic:
+		return "strategy.OnlyTopic"
+	}
+	if s&^all != 0 {
+		return "strategy.INVALID"
+	}
+
+	var names []string
+	for _, bit := range []struct {
+		s    Strategy
+		name string
+	}{{User, "User"}, {Chat, "Chat"}, {Topic, "Topic"}} {
+		if s&bit.s != 0 {
+			names = append(names, bit.name)
+		}
 	}
-	return "strategy.INVALID"
+	return "strategy." + strings.Join(names, "|")
 }
 
-// Storage works over base storage and applies strategy.
+// Address implements Addresser.
+func (s Strategy) Address(key fsm.StorageKey) fsm.StorageKey {
+	return s.applyKey(key)
+}
+
+// Storage works over base storage and applies addressing.
 type Storage struct {
-	storage  fsm.Storage
-	strategy Strategy
+	storage   fsm.Storage
+	addresser Addresser
 }
 
-func NewStorage(storage fsm.Storage, strategy Strategy) *Storage {
-	return &Storage{storage: storage, strategy: strategy}
+// NewStorage returns storage what addresses records by addresser.
+// It can be Strategy, Func or custom implementation.
+//
+//	strategy.NewStorage(base, strategy.User)
+//	strategy.NewStorage(base, strategy.Func(func(key fsm.StorageKey) fsm.StorageKey {
+//		if isAdmin(key.UserID) {
+//			key.UserID = adminsID // shared identity of admins
+//		}
+//		return key
+//	}))
+func NewStorage(storage fsm.Storage, addresser Addresser) *Storage {
+	if addresser == nil {
+		addresser = Empty
+	}
+	return &Storage{storage: storage, addresser: addresser}
 }
 
+// Unwrap returns base storage.
+func (s *Storage) Unwrap() fsm.Storage {
+	return s.storage
+}
+
+// Strategy returns strategy of storage. If storage
+// uses other addresser it returns Empty.
 func (s *Storage) Strategy() Strategy {
-	return s.strategy
+	strategy, _ := s.addresser.(Strategy)
+	return strategy
 }
 
 func (s *Storage) SetStrategy(strategy Strategy) {
-	s.strategy = strategy
+	s.addresser = strategy
+}
+
+// Addresser returns addresser of storage.
+func (s *Storage) Addresser() Addresser {
+	return s.addresser
+}
+
+func (s *Storage) SetAddresser(addresser Addresser) {
+	s.addresser = addresser
 }
 
 func (s *Storage) GetState(c, u int64) (fsm.State, error) {
-	c, u = s.strategy.apply(c, u)
-	return s.storage.GetState(c, u)
+	return s.GetStateByKey(baseKey(c, u))
 }
 
 func (s *Storage) SetState(c, u int64, state fsm.State) error {
-	c, u = s.strategy.apply(c, u)
-	return s.storage.SetState(c, u, state)
+	return s.SetStateByKey(baseKey(c, u), state)
 }
 
 func (s *Storage) ResetState(c, u int64, withData bool) error {
-	c, u = s.strategy.apply(c, u)
-	return s.storage.ResetState(c, u, withData)
+	return s.ResetStateByKey(baseKey(c, u), withData)
 }
 
 func (s *Storage) UpdateData(c, u int64, key string, data any) error {
-	c, u = s.strategy.apply(c, u)
-	return s.storage.UpdateData(c, u, key, data)
+	return s.UpdateDataByKey(baseKey(c, u), key, data)
 }
 
 func (s *Storage) GetData(c, u int64, key string, to any) error {
-	c, u = s.strategy.apply(c, u)
-	return s.storage.GetData(c, u, key, to)
+	return s.GetDataByKey(baseKey(c, u), key, to)
+}
+
+func (s *Storage) GetStateByKey(key fsm.StorageKey) (fsm.State, error) {
+	return fsm.AsKeyStorage(s.storage).GetStateByKey(s.addresser.Address(key))
+}
+
+func (s *Storage) SetStateByKey(key fsm.StorageKey, state fsm.State) error {
+	return fsm.AsKeyStorage(s.storage).SetStateByKey(s.addresser.Address(key), state)
+}
+
+// SwapStateByKey implements fsm.StateSwapper. Base storage must
+// implement it too, otherwise it returns fsm.ErrNotSupported.
+func (s *Storage) SwapStateByKey(key fsm.StorageKey, old, next fsm.State) (bool, error) {
+	swapper, ok := fsm.AsKeyStorage(s.storage).(fsm.StateSwapper)
+	if !ok {
+		return false, fsm.ErrNotSupported
+	}
+	return swapper.SwapStateByKey(s.addresser.Address(key), old, next)
+}
+
+func (s *Storage) ResetStateByKey(key fsm.StorageKey, withData bool) error {
+	return fsm.AsKeyStorage(s.storage).ResetStateByKey(s.addresser.Address(key), withData)
+}
+
+func (s *Storage) UpdateDataByKey(key fsm.StorageKey, dataKey string, data any) error {
+	return fsm.AsKeyStorage(s.storage).UpdateDataByKey(s.addresser.Address(key), dataKey, data)
+}
+
+func (s *Storage) GetDataByKey(key fsm.StorageKey, dataKey string, to any) error {
+	return fsm.AsKeyStorage(s.storage).GetDataByKey(s.addresser.Address(key), dataKey, to)
+}
+
+func baseKey(chat, user int64) fsm.StorageKey {
+	return fsm.StorageKey{ChatID: chat, UserID: user}
+}
+
+// MigrateChat implements fsm.ChatMigrator. Base storage must
+// implement it too, otherwise it returns fsm.ErrNotSupported.
+//
+// Chats are addressed by addresser of storage.
+func (s *Storage) MigrateChat(from, to int64) error {
+	migrator, ok := s.storage.(fsm.ChatMigrator)
+	if !ok {
+		return fsm.ErrNotSupported
+	}
+
+	from = s.addresser.Address(fsm.StorageKey{ChatID: from}).ChatID
+	to = s.addresser.Address(fsm.StorageKey{ChatID: to}).ChatID
+	if from == to {
+		return nil
+	}
+	return migrator.MigrateChat(from, to)
+}
+
+// ResetMember implements fsm.MemberRecordsStorage. Base storage must
+// implement it too, otherwise it returns fsm.ErrNotSupported.
+//
+// Chat and user are addressed by addresser of storage.
+func (s *Storage) ResetMember(chatID, userID int64, withData bool) error {
+	ms, ok := s.storage.(fsm.MemberRecordsStorage)
+	if !ok {
+		return fsm.ErrNotSupported
+	}
+
+	key := s.addresser.Address(fsm.StorageKey{ChatID: chatID, UserID: userID})
+	return ms.ResetMember(key.ChatID, key.UserID, withData)
+}
+
+// DeleteUser implements fsm.UserRecordsStorage. Base storage must
+// implement it too, otherwise it returns fsm.ErrNotSupported.
+//
+// Records are found by user id in base storage, addresser isn't
+// applied. So shared identities of users aren't deleted.
+func (s *Storage) DeleteUser(userID int64) error {
+	us, ok := s.storage.(fsm.UserRecordsStorage)
+	if !ok {
+		return fsm.ErrNotSupported
+	}
+	return us.DeleteUser(userID)
+}
+
+// ExportUser implements fsm.UserRecordsStorage.
+// See DeleteUser for details.
+func (s *Storage) ExportUser(userID int64, w io.Writer) error {
+	us, ok := s.storage.(fsm.UserRecordsStorage)
+	if !ok {
+		return fsm.ErrNotSupported
+	}
+	return us.ExportUser(userID, w)
 }
 
 func (s *Storage) Close() error {
 	return s.storage.Close()
 }
 
+// applyKey applies strategy to chat, user and thread of key.
+// Machine is kept as is.
+func (s Strategy) applyKey(key fsm.StorageKey) fsm.StorageKey {
+	key.ChatID, key.UserID = s.apply(key.ChatID, key.UserID)
+	if s != Empty && (s&Chat == 0 || s&Topic == 0) {
+		key.ThreadID = 0
+	}
+	return key
+}
+
 func (s Strategy) apply(chat, user int64) (int64, int64) {
 	if s == Empty {
 		return chat, user
@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/vitaliy-ukiru/fsm-telebot"
 )
 
 func TestStrategy_apply(t *testing.T) {
@@ -60,3 +61,61 @@ func TestStrategy_apply(t *testing.T) {
 		})
 	}
 }
+
+func TestStrategy_applyKey(t *testing.T) {
+	key := fsm.StorageKey{ChatID: 66, UserID: 88, ThreadID: 5, Machine: "cart"}
+
+	tests := []struct {
+		name string
+		s    Strategy
+		want fsm.StorageKey
+	}{
+		{
+			name: "Default",
+			s:    Default,
+			want: key,
+		},
+		{
+			name: "User and Chat",
+			s:    User | Chat,
+			want: fsm.StorageKey{ChatID: 66, UserID: 88, Machine: "cart"},
+		},
+		{
+			name: "Chat and Topic",
+			s:    Chat | Topic,
+			want: fsm.StorageKey{ChatID: 66, ThreadID: 5, Machine: "cart"},
+		},
+		{
+			name: "Topic without chat",
+			s:    User | Topic,
+			want: fsm.StorageKey{UserID: 88, Machine: "cart"},
+		},
+		{
+			name: "empty strategy (as default)",
+			s:    Empty,
+			want: key,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equalf(t, tt.want, tt.s.applyKey(key), "%s.applyKey(%v)", tt.s, key)
+		})
+	}
+}
+
+func TestStrategy_String(t *testing.T) {
+	tests := []struct {
+		s    Strategy
+		want string
+	}{
+		{Empty, "strategy.Empty"},
+		{Default, "strategy.Default"},
+		{User, "strategy.OnlyUser"},
+		{User | Chat, "strategy.User|Chat"},
+		{Chat | Topic, "strategy.Chat|Topic"},
+		{1, "strategy.INVALID"},
+	}
+	for _, tt := range tests {
+		assert.Equalf(t, tt.want, tt.s.String(), "String(%d)", byte(tt.s))
+	}
+}
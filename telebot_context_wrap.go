@@ -5,42 +5,71 @@ import (
 )
 
 // fsmInternalKey needed for catch context requests.
+// Value under this key always has private type contextValue,
+// so others middlewares can't replace context by accident.
 const fsmInternalKey = "__fsm"
 
+// contextValue is value of fsm context in telebot.Context storage.
+type contextValue struct {
+	ctx        Context
+	dispatched bool
+}
+
 // wrapperContext wraps telebot context and adds fsm
 // context inside.
 // By this wrapper you can get context from any handler
-// under this wrapper.
-//
-// But this very not recommend, because it more internal
-// mechanism.
-// Also, it will have many consequences, because others
-// middlewares can set data with same key (I think it
-// would have been done by accident).
+// under this wrapper. Use FromContext for it.
 //
 // The developers of the package make no guarantee
 // of use outside of this package.
 type wrapperContext struct {
 	tele.Context
-	fsmCtx Context
+	fsmCtx     Context
+	dispatched bool // created by dispatch of manager
 }
 
 func (w *wrapperContext) Get(key string) any {
 	if key == fsmInternalKey {
-		return w.fsmCtx
+		return contextValue{ctx: w.fsmCtx, dispatched: w.dispatched}
 	}
 	return w.Context.Get(key)
 }
 
 func (w *wrapperContext) FSMContext() Context { return w.fsmCtx }
 
-// tryUnwrapContext tries get fsm.Context from telebot.Context.
-func tryUnwrapContext(c tele.Context) (Context, bool) {
-	wrapped, ok := c.(*wrapperContext)
-	if ok {
+// FromContext returns FSM context from telebot context.
+//
+// It works in manager handlers and middlewares, in handlers
+// under middleware.FSMContextMiddleware and for contexts
+// created by WithContext. Also, it works if the context was
+// wrapped by others middlewares.
+func FromContext(c tele.Context) (Context, bool) {
+	if wrapped, ok := c.(*wrapperContext); ok {
 		return wrapped.fsmCtx, true
 	}
 
-	ctx, ok := c.Get(fsmInternalKey).(Context)
-	return ctx, ok
+	v, ok := c.Get(fsmInternalKey).(contextValue)
+	return v.ctx, ok
+}
+
+// WithContext returns telebot context what contains FSM context.
+// FSM context can be got by FromContext.
+func WithContext(c tele.Context, ctx Context) tele.Context {
+	dispatched := isDispatched(c)
+	if wrapped, ok := c.(*wrapperContext); ok {
+		c = wrapped.Context
+	}
+	return &wrapperContext{Context: c, fsmCtx: ctx, dispatched: dispatched}
+}
+
+// isDispatched reports whether context is inside
+// dispatch of manager (see ManagerOf.Bind).
+func isDispatched(c tele.Context) bool {
+	v, _ := c.Get(fsmInternalKey).(contextValue)
+	return v.dispatched
+}
+
+// tryUnwrapContext tries get fsm.Context from telebot.Context.
+func tryUnwrapContext(c tele.Context) (Context, bool) {
+	return FromContext(c)
 }
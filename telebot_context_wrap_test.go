@@ -34,7 +34,7 @@ func Test_wrapperContext_Get(t *testing.T) {
 		{
 			name: "fsm context key",
 			key:  fsmInternalKey,
-			want: fsmCtx,
+			want: contextValue{ctx: fsmCtx},
 		},
 		{
 			name: "base context key",
@@ -61,7 +61,10 @@ func Test_tryUnwrapContext(t *testing.T) {
 	teleCtx := B.NewContext(U)
 	fsmCtx := Context(&fsmContext{c: teleCtx})
 
-	teleCtx.Set(fsmInternalKey, fsmCtx)
+	teleCtx.Set(fsmInternalKey, contextValue{ctx: fsmCtx})
+
+	foreignCtx := B.NewContext(U)
+	foreignCtx.Set(fsmInternalKey, fsmCtx)
 
 	tests := []struct {
 		name  string
@@ -71,7 +74,7 @@ func Test_tryUnwrapContext(t *testing.T) {
 	}{
 		{
 			name:  "wrapped context",
-			args:  args{&wrapperContext{teleCtx, fsmCtx}},
+			args:  args{&wrapperContext{Context: teleCtx, fsmCtx: fsmCtx}},
 			want:  fsmCtx,
 			want1: true,
 		},
@@ -81,6 +84,18 @@ func Test_tryUnwrapContext(t *testing.T) {
 			want:  fsmCtx,
 			want1: true,
 		},
+		{
+			name:  "wrapped by other wrapper",
+			args:  args{struct{ tele.Context }{&wrapperContext{Context: B.NewContext(U), fsmCtx: fsmCtx}}},
+			want:  fsmCtx,
+			want1: true,
+		},
+		{
+			name:  "foreign value of key",
+			args:  args{foreignCtx},
+			want:  nil,
+			want1: false,
+		},
 		{
 			name:  "incorrect context",
 			args:  args{B.NewContext(U)}, // empty context
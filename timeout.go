@@ -0,0 +1,165 @@
+package fsm
+
+import (
+	"sync"
+	"time"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+// timeoutsKey is data key of deadlines of timeouts.
+const timeoutsKey = "__fsm_timeouts"
+
+// Timeout sets inactivity timeout for state. If user is idle in
+// state for duration d, handler h is called with context of user
+// (see RunScheduler). Handler is responsible for the reaction,
+// for example reset state and notify user:
+//
+//	manager.Timeout(InputAgeState, 10*time.Minute, func(c tele.Context, state fsm.Context) error {
+//		if err := state.Finish(true); err != nil {
+//			return err
+//		}
+//		return c.Send("Form expired")
+//	})
+//
+// Activity is any update what manager dispatches for user by handler
+// with storage of manager (not Filter.Storage or router storage). Deadlines
+// are stored in storage of manager, so they survive restarts
+// with persistent storages. Timeout for state has priority over
+// timeout for group (see TimeoutGroup).
+func (m *ManagerOf[C]) Timeout(state State, d time.Duration, h HandlerOf[C]) {
+	t := m.timeouts
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.states[state] = timeoutRule[C]{d: d, h: h}
+}
+
+// TimeoutGroup sets inactivity timeout for all states of group.
+// See Timeout.
+func (m *ManagerOf[C]) TimeoutGroup(group *StateGroup, d time.Duration, h HandlerOf[C]) {
+	t := m.timeouts
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.groups[group.Prefix] = timeoutRule[C]{d: d, h: h}
+}
+
+func newTimeouts[C Context]() *timeouts[C] {
+	return &timeouts[C]{
+		states:    make(map[State]timeoutRule[C]),
+		groups:    make(map[string]timeoutRule[C]),
+		deadlines: registry[timeoutEntry]{dataKey: timeoutsKey},
+	}
+}
+
+// touchTimeout updates deadline of user by current state.
+func (m *ManagerOf[C]) touchTimeout(c tele.Context, state Context) error {
+	if m.timeouts == nil || m.timeouts.empty() {
+		return nil
+	}
+
+	key, ok := DefaultAddressing.Resolve(c)
+	if !ok {
+		return nil
+	}
+	current, err := state.State()
+	if err != nil {
+		return err
+	}
+	return m.timeouts.touch(m.store, key, current, time.Now())
+}
+
+// fireTimeouts calls handler of expired timeout of key
+// if user still is in state.
+func (m *ManagerOf[C]) fireTimeouts(key StorageKey, now time.Time) {
+	unlock, err := m.lockKey(key)
+	if err != nil {
+		m.bot.OnError(err, nil)
+		return
+	}
+	defer unlock()
+
+	// deadline can be moved by activity after indexing
+	expired, err := m.timeouts.deadlines.expired(m.store, key, now)
+	if err != nil || len(expired) == 0 {
+		if err != nil {
+			m.bot.OnError(err, nil)
+		}
+		return
+	}
+	e := expired[0] // key has one deadline
+
+	c, state := m.contextFor(key)
+	current, err := state.State()
+	if err != nil {
+		m.bot.OnError(err, c)
+		return
+	}
+
+	rule, ok := m.timeouts.rule(current)
+	if !ok || current != e.State {
+		return // user left state without manager
+	}
+	if err := rule.h(c, state); err != nil {
+		m.bot.OnError(err, c)
+	}
+}
+
+type timeoutRule[C Context] struct {
+	d time.Duration
+	h HandlerOf[C]
+}
+
+// timeoutID is id of deadline, key has one deadline.
+const timeoutID = "deadline"
+
+// timeoutEntry is deadline of user in state.
+type timeoutEntry struct {
+	State    State     `json:"state"`
+	Deadline time.Time `json:"deadline"`
+}
+
+func (e timeoutEntry) id() string     { return timeoutID }
+func (e timeoutEntry) due() time.Time { return e.Deadline }
+
+// timeouts is registry of timeouts.
+type timeouts[C Context] struct {
+	mu     sync.Mutex // guards rules
+	states map[State]timeoutRule[C]
+	groups map[string]timeoutRule[C]
+
+	deadlines registry[timeoutEntry]
+}
+
+// empty reports whether registry has no rules.
+func (t *timeouts[C]) empty() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.states) == 0 && len(t.groups) == 0
+}
+
+// rule returns rule for state.
+func (t *timeouts[C]) rule(state State) (timeoutRule[C], bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if rule, ok := t.states[state]; ok {
+		return rule, true
+	}
+	prefix, _ := state.Parse()
+	rule, ok := t.groups[prefix]
+	return rule, ok && prefix != ""
+}
+
+// touch sets deadline for key by state. If state
+// has no timeout deadline of key is deleted.
+func (t *timeouts[C]) touch(storage Storage, key StorageKey, state State, now time.Time) error {
+	rule, ok := t.rule(state)
+	if !ok {
+		return t.deadlines.remove(storage, key, timeoutID)
+	}
+
+	return t.deadlines.put(storage, key, timeoutEntry{
+		State:    state,
+		Deadline: now.Add(rule.d),
+	})
+}
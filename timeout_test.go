@@ -0,0 +1,124 @@
+package fsm_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vitaliy-ukiru/fsm-telebot"
+	"github.com/vitaliy-ukiru/fsm-telebot/storages/memory"
+	tele "gopkg.in/telebot.v3"
+)
+
+func TestManager_Timeout(t *testing.T) {
+	storage := memory.NewStorage()
+	sg := fsm.NewStateGroup("form", "name", "age")
+
+	// first instance of bot moves user into group
+	{
+		bot := newTestBot(t)
+		m := fsm.NewManager(bot, nil, storage, nil)
+		m.TimeoutGroup(sg, 20*time.Millisecond, func(tele.Context, fsm.Context) error {
+			t.Error("timeout fired in first instance")
+			return nil
+		})
+		m.Bind(tele.OnText, fsm.DefaultState, func(_ tele.Context, state fsm.Context) error {
+			return state.Set(sg.First())
+		})
+		bot.ProcessUpdate(textUpdate("start"))
+	}
+
+	// restarted instance fires timeout
+	bot := newTestBot(t)
+	m := fsm.NewManager(bot, nil, storage, nil)
+
+	fired := make(chan fsm.StorageKey, 1)
+	m.TimeoutGroup(sg, 20*time.Millisecond, func(c tele.Context, state fsm.Context) error {
+		fired <- fsm.StorageKey{ChatID: c.Chat().ID, UserID: c.Sender().ID}
+		return state.Finish(true)
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	go m.RunScheduler(ctx, 5*time.Millisecond)
+
+	select {
+	case key := <-fired:
+		assert.Equal(t, fsm.StorageKey{ChatID: 10, UserID: 20}, key, "key of context")
+	case <-ctx.Done():
+		t.Fatal("timeout didn't fire")
+	}
+	cancel()
+
+	state, err := storage.GetState(10, 20)
+	require.NoError(t, err)
+	assert.Equal(t, fsm.DefaultState, state, "state after timeout")
+}
+
+func TestManager_TimeoutLeftState(t *testing.T) {
+	bot := newTestBot(t)
+	storage := memory.NewStorage()
+	m := fsm.NewManager(bot, nil, storage, nil)
+
+	m.Timeout("wait", 10*time.Millisecond, func(tele.Context, fsm.Context) error {
+		t.Error("timeout fired after leaving state")
+		return nil
+	})
+	m.Bind(tele.OnText, fsm.DefaultState, func(_ tele.Context, state fsm.Context) error {
+		return state.Set("wait")
+	})
+	m.Bind(tele.OnText, "wait", func(_ tele.Context, state fsm.Context) error {
+		return state.Set("done")
+	})
+
+	bot.ProcessUpdate(textUpdate("start"))
+	bot.ProcessUpdate(textUpdate("next"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	m.RunScheduler(ctx, 5*time.Millisecond)
+}
+
+func TestManager_TimeoutInstances(t *testing.T) {
+	storage := memory.NewStorage()
+
+	fired := make(chan int64, 2)
+	newInstance := func() (*tele.Bot, *fsm.Manager) {
+		bot := newTestBot(t)
+		m := fsm.NewManager(bot, nil, storage, nil)
+		m.Timeout("wait", 10*time.Millisecond, func(c tele.Context, state fsm.Context) error {
+			fired <- c.Sender().ID
+			return state.Finish(true)
+		})
+		m.Bind(tele.OnText, fsm.AnyState, func(_ tele.Context, state fsm.Context) error {
+			return state.Set("wait")
+		})
+		return bot, m
+	}
+
+	// instances share storage, every one gets own user
+	bot1, m1 := newInstance()
+	bot2, _ := newInstance()
+	bot1.ProcessUpdate(textUpdate("first"))
+	other := textUpdate("second")
+	other.Message.Sender = &tele.User{ID: 21}
+	bot2.ProcessUpdate(other)
+	bot1.ProcessUpdate(textUpdate("first again"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	go m1.RunScheduler(ctx, 5*time.Millisecond)
+
+	var users []int64
+	for len(users) < 2 {
+		select {
+		case id := <-fired:
+			users = append(users, id)
+		case <-ctx.Done():
+			t.Fatalf("timeouts didn't fire, fired for %v", users)
+		}
+	}
+	assert.ElementsMatch(t, []int64{20, 21}, users)
+}
@@ -0,0 +1,85 @@
+package fsm
+
+import (
+	tele "gopkg.in/telebot.v3"
+)
+
+// TransitionHandlerOf is handler what returns next state.
+// See ManagerOf.BindTransition.
+type TransitionHandlerOf[C Context] func(c tele.Context, state C) (State, error)
+
+// TransitionHandler is handler what returns next state.
+//
+//	func OnInputAge(c tele.Context, state fsm.Context) (fsm.State, error) {
+//		age, err := strconv.Atoi(c.Text())
+//		if err != nil {
+//			return InputAgeState, c.Send("Incorrect age. Retry again.")
+//		}
+//		return InputHobbyState, state.Update("age", age)
+//	}
+type TransitionHandler = TransitionHandlerOf[Context]
+
+// TransitionHookOf is called before manager sets state
+// returned by transition handler. If hook returns error
+// state will not be set.
+type TransitionHookOf[C Context] func(c tele.Context, state C, next State) error
+
+// TransitionHook is called before manager sets state
+// returned by transition handler.
+type TransitionHook = TransitionHookOf[Context]
+
+// OnTransition adds hooks what are called before every
+// transition from transition handlers. Hooks can validate
+// transition or run entry actions for states.
+func (m *ManagerOf[C]) OnTransition(hooks ...TransitionHookOf[C]) {
+	m.hooks = append(m.hooks, hooks...)
+}
+
+// BindTransition adds transition handler with filter on state.
+//
+// Manager sets state what handler returns only if handler
+// succeeded (returned nil error).
+func (m *ManagerOf[C]) BindTransition(end any, state State, h TransitionHandlerOf[C], middlewares ...tele.MiddlewareFunc) {
+	m.Bind(end, state, m.transition(h), middlewares...)
+}
+
+// HandleTransition adds transition handler with filter on states.
+// See BindTransition and Handle for details.
+func (m *ManagerOf[C]) HandleTransition(f Filter, h TransitionHandlerOf[C], middlewares ...tele.MiddlewareFunc) {
+	m.Handle(f, m.transition(h), middlewares...)
+}
+
+// BindTransition adds transition handler with filter on state.
+// See ManagerOf.BindTransition.
+func (r *RouterOf[C]) BindTransition(end any, state State, h TransitionHandlerOf[C], middlewares ...tele.MiddlewareFunc) {
+	r.Bind(end, state, r.m.transition(h), middlewares...)
+}
+
+// HandleTransition adds transition handler with filter on states.
+// See ManagerOf.BindTransition.
+func (r *RouterOf[C]) HandleTransition(f Filter, h TransitionHandlerOf[C], middlewares ...tele.MiddlewareFunc) {
+	r.Handle(f, r.m.transition(h), middlewares...)
+}
+
+// BindTransition adds transition handler with filter on
+// state of group by name. See ManagerOf.BindTransition.
+func (r *GroupRouterOf[C]) BindTransition(end any, name string, h TransitionHandlerOf[C], middlewares ...tele.MiddlewareFunc) {
+	r.Bind(end, name, r.m.transition(h), middlewares...)
+}
+
+// transition converts transition handler to handler.
+func (m *ManagerOf[C]) transition(h TransitionHandlerOf[C]) HandlerOf[C] {
+	return func(c tele.Context, state C) error {
+		next, err := h(c, state)
+		if err != nil {
+			return err
+		}
+
+		for _, hook := range m.hooks {
+			if err := hook(c, state, next); err != nil {
+				return err
+			}
+		}
+		return state.Set(next)
+	}
+}
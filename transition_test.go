@@ -0,0 +1,63 @@
+package fsm_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vitaliy-ukiru/fsm-telebot"
+	"github.com/vitaliy-ukiru/fsm-telebot/storages/memory"
+	tele "gopkg.in/telebot.v3"
+)
+
+func TestManager_BindTransition(t *testing.T) {
+	var (
+		errInvalid = errors.New("invalid input")
+		errDenied  = errors.New("denied")
+	)
+
+	tests := []struct {
+		name    string
+		text    string
+		hookErr error
+		want    fsm.State
+		wantErr error
+	}{
+		{name: "success", text: "ok", want: "next"},
+		{name: "handler error", text: "bad", want: "start", wantErr: errInvalid},
+		{name: "hook error", text: "ok", hookErr: errDenied, want: "start", wantErr: errDenied},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotErr error
+			bot, err := tele.NewBot(tele.Settings{
+				Synchronous: true,
+				Offline:     true,
+				OnError:     func(err error, _ tele.Context) { gotErr = err },
+			})
+			require.NoError(t, err)
+
+			storage := memory.NewStorage()
+			m := fsm.NewManager(bot, nil, storage, nil)
+			m.OnTransition(func(_ tele.Context, _ fsm.Context, next fsm.State) error {
+				assert.Equal(t, fsm.State("next"), next, "hook next state")
+				return tt.hookErr
+			})
+			m.BindTransition(tele.OnText, "start", func(c tele.Context, _ fsm.Context) (fsm.State, error) {
+				if c.Text() != "ok" {
+					return "start", errInvalid
+				}
+				return "next", nil
+			})
+
+			require.NoError(t, storage.SetState(10, 20, "start"))
+			bot.ProcessUpdate(textUpdate(tt.text))
+
+			assert.ErrorIs(t, gotErr, tt.wantErr)
+			state, err := storage.GetState(10, 20)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, state)
+		})
+	}
+}